@@ -0,0 +1,73 @@
+package api
+
+import (
+	"github.com/iota-tangle-io/spamalot-coo/api"
+	"github.com/iota-tangle-io/spamalot-slave/backend/pow"
+	"github.com/iota-tangle-io/spamalot-slave/backend/spam"
+	"runtime"
+)
+
+// message types the slave exchanges with the coordinator in addition to the coordinator's API.
+// they are untyped so that they can be used with the coordinator's message type.
+const (
+	// sent by the slave after the welcome, payload is a SlaveCapabilitiesMsg
+	SLAVE_CAPABILITIES = 100
+	// sent by the slave if a spammer configuration from the coordinator is rejected, payload is a ConfigInvalidMsg
+	SLAVE_CONFIG_INVALID = 101
+	// sent by the slave for each event of the spammer, payload is a spam.Event
+	SLAVE_EVENT = 102
+	// sent by the slave if a spammer command failed, payload is a SpammerErrorMsg
+	SLAVE_SPAMMER_ERROR = 103
+	// sent by the slave if a subscription of the coordinator is rejected, payload is a SpammerErrorMsg
+	SLAVE_SUBSCRIPTION_INVALID = 104
+	// sent by the slave if a log level change of the coordinator is rejected, payload is a SpammerErrorMsg
+	SLAVE_LOG_LEVEL_INVALID = 105
+
+	// sent by the coordinator to select the metrics it receives, payload is a spam.Subscription
+	SP_SUBSCRIBE = 110
+	// sent by the coordinator to change the log level of a component, payload is a LogLevelMsg
+	SP_SET_LOG_LEVEL = 111
+)
+
+// SlaveCapabilitiesMsg describes what the slave's host is capable of.
+type SlaveCapabilitiesMsg struct {
+	NumCPU   int      `json:"num_cpu"`
+	PoWImpls []string `json:"pow_impls"`
+	BestPoW  string   `json:"best_pow"`
+	// last PoW benchmark result, nil if no benchmark was run yet
+	Benchmark *pow.BenchmarkResult `json:"benchmark"`
+}
+
+func newCapabilitiesMsg() SlaveCapabilitiesMsg {
+	return SlaveCapabilitiesMsg{
+		NumCPU:    runtime.NumCPU(),
+		PoWImpls:  pow.Available(),
+		BestPoW:   pow.Best(),
+		Benchmark: pow.LastBenchmark(),
+	}
+}
+
+// SpammerStateMsg is the coordinator's spammer state message extended with slave only state.
+type SpammerStateMsg struct {
+	api.SlaveSpammerStateMsg
+	PoW       string `json:"pow,omitempty"`
+	PoWSource string `json:"pow_source,omitempty"`
+	// why the spammer stopped on its own, empty if it didn't
+	StopReason string `json:"stop_reason,omitempty"`
+}
+
+// ConfigInvalidMsg lists why a spammer configuration was rejected.
+type ConfigInvalidMsg struct {
+	Errors spam.ValidationErrors `json:"errors"`
+}
+
+// SpammerErrorMsg carries the error of a failed spammer command.
+type SpammerErrorMsg struct {
+	Error string `json:"error"`
+}
+
+// LogLevelMsg changes the log level of a component of the slave, an empty component sets the default level.
+type LogLevelMsg struct {
+	Component string `json:"component"`
+	Level     string `json:"level"`
+}
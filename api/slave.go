@@ -7,37 +7,112 @@ import (
 	"github.com/iota-tangle-io/spamalot-slave/backend/utilities"
 	"github.com/iota-tangle-io/spamalot-coo/api"
 	"github.com/iota-tangle-io/iota-spamalot.go"
+	"github.com/iota-tangle-io/spamalot-slave/backend/history"
+	"github.com/iota-tangle-io/spamalot-slave/backend/models"
+	"github.com/iota-tangle-io/spamalot-slave/backend/prom"
+	"github.com/iota-tangle-io/spamalot-slave/backend/spam"
+	"github.com/iota-tangle-io/spamalot-slave/backend/txlog"
 	"encoding/json"
 	"fmt"
-	"github.com/CWarner818/giota"
 	"github.com/pkg/errors"
-	"crypto/md5"
-	"encoding/hex"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 )
 
+var (
+	cooConnectedGauge = prom.Default.Gauge("spamalot_coordinator_connected",
+		"Whether the slave is connected to the coordinator.")
+	cooLastMsgGauge = prom.Default.Gauge("spamalot_coordinator_last_msg_timestamp_seconds",
+		"Unix time of the last message received from the coordinator.")
+)
+
+// name of the coordinator driven spammer in metrics and the history
+const coordinatorSpammer = "coordinator"
+
+// metrics sent to the coordinator until it subscribes to specific ones
+var defaultCooSubscription = spam.Subscription{
+	Kinds: []spamalot.MetricType{
+		spamalot.SUMMARY, spamalot.INC_SUCCESSFUL_TX, spam.CONFIRMATION_SUMMARY, spam.INC_CONFIRMED_TX,
+	},
+}
+
 func NewSlave(cooAddress string, apiToken string) *Slave {
 	slave := &Slave{CooAddress: cooAddress, APIToken: apiToken}
+	filter, err := spam.NewMetricFilter(defaultCooSubscription)
+	if err != nil {
+		panic(err)
+	}
+	slave.metricFilter = filter
 	return slave
 }
 
 type Slave struct {
-	CooAddress    string
-	APIToken      string
-	logger        log15.Logger
-	ws            *websocket.Conn
-	spammerConfig *api.SpammerConfig
-	spammer       *spamalot.Spammer
-	metrics       chan spamalot.Metric
+	CooAddress   string
+	APIToken     string
+	History      *history.Store `inject:""`
+	TxLog        *txlog.Log     `inject:""`
+	logger       log15.Logger
+	ws           *websocket.Conn
+	metrics      chan spam.Metric
+	muFilter     sync.Mutex
+	metricFilter *spam.MetricFilter
+
+	// written by the communicate loop only, guarded by muSpammer for readers outside of it
+	muSpammer     sync.Mutex
+	spammerConfig *models.SpammerConfig
+	spammer       *spam.Spammer
+
+	muLink sync.Mutex
+	link   LinkState
 
 	// use channels to enfore max one reader and writer throughout the slave
 	wsWrite chan *api.SlaveMsg
 	wsRead  chan *api.CooMsg
+	// signals the communicate loop that the spammer stopped on its own
+	autoStopped chan struct{}
+}
+
+// LinkState describes the connection to the coordinator.
+type LinkState struct {
+	// whether the slave tries to connect to the coordinator at all
+	Enabled   bool      `json:"enabled"`
+	Connected bool      `json:"connected"`
+	LastMsg   time.Time `json:"last_msg"`
+}
+
+// Link returns the state of the connection to the coordinator.
+func (slave *Slave) Link() LinkState {
+	slave.muLink.Lock()
+	defer slave.muLink.Unlock()
+	return slave.link
+}
+
+// Spammer returns the spammer run for the coordinator and its configuration, both nil until the coordinator sent one.
+func (slave *Slave) Spammer() (*spam.Spammer, *models.SpammerConfig) {
+	slave.muSpammer.Lock()
+	defer slave.muSpammer.Unlock()
+	return slave.spammer, slave.spammerConfig
+}
+
+func (slave *Slave) setConnected(connected bool) {
+	slave.muLink.Lock()
+	slave.link.Connected = connected
+	slave.muLink.Unlock()
+	if connected {
+		cooConnectedGauge.Set(1)
+	} else {
+		cooConnectedGauge.Set(0)
+	}
 }
 
 func (slave *Slave) Connect() {
+	slave.muLink.Lock()
+	slave.link.Enabled = true
+	slave.muLink.Unlock()
+
 	logger, err := utilities.GetLogger("slave")
 	if err != nil {
 		// TODO: replace in the future
@@ -45,9 +120,10 @@ func (slave *Slave) Connect() {
 	}
 
 	slave.logger = logger.New("address", slave.CooAddress)
-	slave.metrics = make(chan spamalot.Metric)
+	slave.metrics = make(chan spam.Metric)
 	slave.wsWrite = make(chan *api.SlaveMsg)
 	slave.wsRead = make(chan *api.CooMsg)
+	slave.autoStopped = make(chan struct{}, 1)
 
 	u := url.URL{Scheme: "ws", Host: slave.CooAddress, Path: "/api"}
 	slave.logger.Info("connecting to coordinator")
@@ -96,16 +172,23 @@ func (slave *Slave) Connect() {
 	case api.SLAVE_WELCOME:
 
 		// grab spammer configuration
-		spammerConfig := &api.SpammerConfig{}
+		spammerConfig := &models.SpammerConfig{}
 		if err := json.Unmarshal(cooMsg.Payload, spammerConfig); err != nil {
 			slave.logger.Info("unable to parse spammer configuration from coo, canceling conn,", "err", err.Error())
 			slave.sendInternalErrorCode()
 			return
 		}
-		slave.spammerConfig = spammerConfig
+		if err := spam.Validate(spammerConfig); err != nil {
+			slave.logger.Warn("spammer configuration from coo is invalid, canceling conn", "err", err.Error())
+			slave.writeConfigInvalid(err)
+			return
+		}
+		slave.setSpammerConfig(spammerConfig)
 
 		slave.logger.Info("coordinator connection successful")
+		slave.setConnected(true)
 		slave.communicate()
+		slave.setConnected(false)
 	default:
 		slave.logger.Warn("received undefined msg from coordinator")
 	}
@@ -118,6 +201,39 @@ func (slave *Slave) sendInternalErrorCode() {
 	slave.wsWrite <- &api.SlaveMsg{Type: api.SLAVE_INTERNAL_ERROR}
 }
 
+// sendConfigInvalid tells the coordinator which fields of its spammer configuration are invalid
+func (slave *Slave) sendConfigInvalid(err error) {
+	msg, err := newConfigInvalidMsg(err)
+	if err != nil {
+		slave.logger.Warn("unable to construct config invalid msg", "err", err.Error())
+		slave.sendInternalErrorCode()
+		return
+	}
+	slave.wsWrite <- msg
+}
+
+// writeConfigInvalid is sendConfigInvalid for the handshake, before the send channel is opened
+func (slave *Slave) writeConfigInvalid(err error) {
+	msg, err := newConfigInvalidMsg(err)
+	if err != nil {
+		slave.logger.Warn("unable to construct config invalid msg", "err", err.Error())
+		msg = &api.SlaveMsg{Type: api.SLAVE_INTERNAL_ERROR}
+	}
+	if err := slave.ws.WriteJSON(msg); err != nil {
+		slave.logger.Warn("unable to send config invalid msg", "err", err.Error())
+	}
+}
+
+func newConfigInvalidMsg(err error) (*api.SlaveMsg, error) {
+	payload := ConfigInvalidMsg{}
+	if errs, ok := err.(spam.ValidationErrors); ok {
+		payload.Errors = errs
+	} else {
+		payload.Errors = spam.ValidationErrors{{Msg: err.Error()}}
+	}
+	return api.NewSlaveMsg(SLAVE_CONFIG_INVALID, payload)
+}
+
 func (slave *Slave) printSpammerConfig() {
 	if slave.spammerConfig == nil {
 		return
@@ -133,8 +249,13 @@ func (slave *Slave) printSpammerConfig() {
 
 func (slave *Slave) receiveMetrics() {
 	for metric := range slave.metrics {
-		// only send summaries for now
-		if metric.Kind != spamalot.SUMMARY && metric.Kind != spamalot.INC_SUCCESSFUL_TX {
+		if metric.Kind == spamalot.SUMMARY {
+			slave.record(metric)
+		}
+		slave.muFilter.Lock()
+		filter := slave.metricFilter
+		slave.muFilter.Unlock()
+		if !filter.Allow(metric.Kind) {
 			continue
 		}
 		metricJSON, err := json.Marshal(&metric)
@@ -148,6 +269,21 @@ func (slave *Slave) receiveMetrics() {
 	}
 }
 
+// record persists the given summary in the history
+func (slave *Slave) record(metric spam.Metric) {
+	if slave.History == nil {
+		return
+	}
+	point := history.Point{TS: time.Now(), Spammer: coordinatorSpammer}
+	if err := spam.DecodeData(metric.Data, &point.Summary); err != nil {
+		slave.logger.Warn("unable to decode summary", "err", err.Error())
+		return
+	}
+	if err := slave.History.Append(point); err != nil {
+		slave.logger.Warn("unable to persist summary", "err", err.Error())
+	}
+}
+
 func (slave *Slave) openReceiveChannel() {
 exit:
 	for {
@@ -156,6 +292,11 @@ exit:
 			slave.logger.Warn("unable to read coo msg", "err", err.Error())
 			break exit
 		}
+		now := time.Now()
+		slave.muLink.Lock()
+		slave.link.LastMsg = now
+		slave.muLink.Unlock()
+		cooLastMsgGauge.Set(float64(now.Unix()))
 		slave.wsRead <- cooMsg
 	}
 	close(slave.wsRead)
@@ -188,7 +329,7 @@ func (slave *Slave) communicate() {
 		slave.sendInternalErrorCode()
 		return
 	}
-	slave.spammer = spammer
+	slave.setSpammer(spammer)
 
 	go slave.openReceiveChannel()
 	go slave.openSendChannel()
@@ -196,46 +337,86 @@ func (slave *Slave) communicate() {
 
 	// send spammer state
 	slave.sendSpammerState()
+	slave.sendCapabilities()
 
 	for {
-		cooMsg, ok := <-slave.wsRead
-		if !ok {
-			break
+		var cooMsg *api.CooMsg
+		select {
+		case <-slave.autoStopped:
+			// tell the coordinator about the spammer having stopped on its own
+			slave.sendSpammerState()
+			continue
+		case msg, ok := <-slave.wsRead:
+			if !ok {
+				return
+			}
+			cooMsg = msg
 		}
 
 		// obey to the coordinator
 		switch cooMsg.Type {
 		case api.SP_START:
-			slave.startSpammer()
 			slave.logger.Info("got spammer start msg")
+			if err := slave.startSpammer(); err != nil {
+				slave.sendSpammerError(err)
+			}
 
 		case api.SP_STOP:
 			slave.logger.Info("got spammer stop msg")
-			slave.stopSpammer()
+			if err := slave.stopSpammer(); err != nil {
+				slave.sendSpammerError(err)
+			}
 
 		case api.SP_RESTART:
 			slave.logger.Info("got spammer restart msg")
-			slave.restartSpammer()
+			if err := slave.restartSpammer(); err != nil {
+				slave.sendSpammerError(err)
+			}
 
 		case api.SP_RESET_CONFIG:
-			wasRunning := slave.spammer.IsRunning()
+			slave.logger.Info("got spammer reset config msg")
+			spammerConfig, err := slave.parseSpammerConfig(cooMsg.Payload)
+			if err != nil {
+				continue
+			}
+
+			// changed runtime settings are applied without replacing the spammer
+			if slave.updateRuntime(spammerConfig) {
+				break
+			}
+
+			// a spammer waiting to be restarted by its supervisor counts as running
+			wasRunning := slave.spammer.Desired()
 			if err := slave.stopSpammer(); err != nil {
 				continue
 			}
-			slave.logger.Info("got spammer reset config msg")
-			if err := slave.configureSpammer(cooMsg.Payload); err != nil {
+			if err := slave.configureSpammer(spammerConfig); err != nil {
 				slave.logger.Warn("couldn't reset configuration")
 				continue
 			}
 
 			if wasRunning {
 				if err := slave.startSpammer(); err != nil {
-					continue
+					slave.sendSpammerError(err)
 				}
 			}
 		case api.SP_METRICS:
 			slave.logger.Info("got spammer metrics msg")
 
+		case SP_SET_LOG_LEVEL:
+			slave.logger.Info("got log level msg")
+			if err := slave.setLogLevel(cooMsg.Payload); err != nil {
+				slave.logger.Warn("rejected log level change", "err", err.Error())
+				slave.sendError(SLAVE_LOG_LEVEL_INVALID, err)
+			}
+
+		case SP_SUBSCRIBE:
+			slave.logger.Info("got metric subscription msg")
+			if err := slave.subscribe(cooMsg.Payload); err != nil {
+				slave.logger.Warn("rejected metric subscription", "err", err.Error())
+				slave.sendError(SLAVE_SUBSCRIPTION_INVALID, err)
+			}
+
 		default:
 			slave.logger.Warn("got an unknown msg type from coo", "code", cooMsg.Type)
 		}
@@ -247,8 +428,8 @@ func (slave *Slave) communicate() {
 }
 
 func (slave *Slave) sendSpammerState() {
-	payload := api.SlaveSpammerStateMsg{}
-	configBytes, err := json.Marshal(slave.spammerConfig)
+	payload := SpammerStateMsg{}
+	configHash, err := spam.ConfigHash(slave.spammerConfig)
 	if err != nil {
 		slave.logger.Warn("unable to marshal current config")
 		slave.sendInternalErrorCode()
@@ -256,10 +437,11 @@ func (slave *Slave) sendSpammerState() {
 	}
 
 	// create state message
-	hasher := md5.New()
-	hasher.Write(configBytes)
-	payload.ConfigHash = hex.EncodeToString(hasher.Sum(nil))
+	payload.ConfigHash = configHash
 	payload.Running = slave.spammer.IsRunning()
+	payload.PoW = slave.spammer.PoW()
+	payload.PoWSource = slave.spammer.PoWSource()
+	payload.StopReason = slave.spammer.StopReason()
 
 	msg, err := api.NewSlaveMsg(api.SLAVE_SPAMMER_STATE, payload)
 	if err != nil {
@@ -273,6 +455,16 @@ func (slave *Slave) sendSpammerState() {
 	slave.logger.Info("state msg sent to coo")
 }
 
+func (slave *Slave) sendCapabilities() {
+	msg, err := api.NewSlaveMsg(SLAVE_CAPABILITIES, newCapabilitiesMsg())
+	if err != nil {
+		slave.logger.Warn("unable to construct capabilities msg", "err", err.Error())
+		slave.sendInternalErrorCode()
+		return
+	}
+	slave.wsWrite <- msg
+}
+
 var ErrSpammerNotInitialised = errors.New("spammer is not initialised")
 
 func (slave *Slave) printSlaveNotInitMsg() {
@@ -296,10 +488,7 @@ func (slave *Slave) stopSpammer() error {
 		return ErrSpammerNotInitialised
 	}
 
-	if !slave.spammer.IsRunning() {
-		return nil
-	}
-
+	// also cancels a restart pending after the spammer exited on its own
 	slave.logger.Info("halting spammer...")
 	if err := slave.spammer.Stop(); err != nil {
 		slave.logger.Warn("couldn't stop spammer", "err", err.Error())
@@ -320,59 +509,161 @@ func (slave *Slave) startSpammer() error {
 	}
 
 	slave.logger.Info("starting spammer...")
-	go slave.spammer.Start() // don't actually do it for now
+	if err := slave.spammer.StartWait(spam.DefaultStartTimeout); err != nil {
+		slave.logger.Warn("couldn't start spammer", "err", err.Error())
+		return err
+	}
 	slave.logger.Info("spammer started")
 	return nil
 }
 
-func (slave *Slave) configureSpammer(payload []byte) error {
-	spammerConfig := &api.SpammerConfig{}
+// parseSpammerConfig parses and validates a spammer configuration sent by the coordinator.
+// the coordinator is informed about invalid fields.
+func (slave *Slave) parseSpammerConfig(payload []byte) (*models.SpammerConfig, error) {
+	spammerConfig := &models.SpammerConfig{}
 	if err := json.Unmarshal(payload, spammerConfig); err != nil {
 		slave.logger.Warn("unable to parse new spammer config", "err", err.Error())
-		return err
+		slave.sendConfigInvalid(err)
+		return nil, err
+	}
+	if err := spam.Validate(spammerConfig); err != nil {
+		slave.logger.Warn("new spammer config is invalid", "err", err.Error())
+		slave.sendConfigInvalid(err)
+		return nil, err
+	}
+	return spammerConfig, nil
+}
+
+// updateRuntime applies the target rate and resource limits of the given config to the
+// current spammer if the config doesn't differ in anything else. returns whether it was applied.
+func (slave *Slave) updateRuntime(spammerConfig *models.SpammerConfig) bool {
+	if slave.spammer == nil || slave.spammerConfig == nil {
+		return false
+	}
+
+	if !spammerConfig.EqualsIgnoringRuntime(*slave.spammerConfig) {
+		return false
 	}
 
+	slave.logger.Info("got spammer runtime settings update", "tps", spammerConfig.TargetTPS, "burst", spammerConfig.Burst,
+		"pow_threads", spammerConfig.PoWThreads, "max_procs", spammerConfig.MaxProcs, "niceness", spammerConfig.Niceness)
+	slave.spammer.UpdateRuntime(spammerConfig)
+	return true
+}
+
+func (slave *Slave) configureSpammer(spammerConfig *models.SpammerConfig) error {
 	slave.logger.Info("spammer configuration from coo:")
 
 	// reset config so newSpammer() will create a spammer with the new config
-	slave.spammerConfig = spammerConfig
+	previous := slave.spammerConfig
+	slave.setSpammerConfig(spammerConfig)
 	slave.printSpammerConfig()
 
 	// previous spammer should be stopped
 	spammer, err := slave.newSpammer()
 	if err != nil {
+		// fall back to the previous configuration, the previous spammer is closed already
+		slave.setSpammerConfig(previous)
+		if spammer, prevErr := slave.newSpammer(); prevErr != nil {
+			slave.logger.Warn("unable to recreate spammer with previous config", "err", prevErr.Error())
+		} else {
+			slave.setSpammer(spammer)
+		}
 		return err
 	}
-	slave.spammer = spammer
+	slave.setSpammer(spammer)
 	return nil
 }
 
-func (slave *Slave) newSpammer() (*spamalot.Spammer, error) {
-	config := slave.spammerConfig
-	spammer, err := spamalot.New(
-		spamalot.WithMWM(int64(config.MWM)),
-		spamalot.WithDepth(int64(config.Depth)),
-		spamalot.ToAddress(config.DestAddress),
-		spamalot.WithTag(config.Tag),
-		spamalot.WithMessage(config.Message),
-		spamalot.WithSecurityLevel(spamalot.SecurityLevel(config.SecurityLvl)),
-		spamalot.FilterTrunk(config.FilterTrunk),
-		spamalot.FilterBranch(config.FilterTrunk),
-		spamalot.FilterMilestone(config.FilterMilestone),
-		spamalot.WithMetricsRelay(slave.metrics),
-	)
+func (slave *Slave) setSpammerConfig(spammerConfig *models.SpammerConfig) {
+	slave.muSpammer.Lock()
+	slave.spammerConfig = spammerConfig
+	slave.muSpammer.Unlock()
+}
+
+func (slave *Slave) setSpammer(spammer *spam.Spammer) {
+	slave.muSpammer.Lock()
+	slave.spammer = spammer
+	slave.muSpammer.Unlock()
+}
+
+func (slave *Slave) newSpammer() (*spam.Spammer, error) {
+	// free the gateway of the previous spammer
+	if slave.spammer != nil {
+		if err := slave.spammer.Close(); err != nil {
+			return nil, err
+		}
+	}
+	spammer, err := spam.New(slave.spammerConfig, slave.metrics, slave.logger)
 	if err != nil {
 		return nil, err
 	}
+	spammer.SetName(coordinatorSpammer)
+	if slave.TxLog != nil {
+		spammer.SetTxLog(slave.TxLog)
+	}
+	// the state is sent by the communicate loop, a pending signal covers any further stops
+	spammer.OnAutoStop = func(reason string) {
+		select {
+		case slave.autoStopped <- struct{}{}:
+		default:
+		}
+	}
+	spammer.OnEvent = slave.sendEvent
+	return spammer, nil
+}
 
-	// configure PoW
-	if config.PoWMode == api.POW_LOCAL {
-		_, pow := giota.GetBestPoW()
-		spammer.UpdateSettings(spamalot.WithPoW(pow))
-		spammer.UpdateSettings(spamalot.WithNode(config.NodeAddress, false))
-	} else {
-		spammer.UpdateSettings(spamalot.WithNode(config.NodeAddress, true))
+// sendSpammerError tells the coordinator why a command on the spammer failed
+func (slave *Slave) sendSpammerError(err error) {
+	msg, err := api.NewSlaveMsg(SLAVE_SPAMMER_ERROR, SpammerErrorMsg{Error: err.Error()})
+	if err != nil {
+		slave.logger.Warn("unable to construct spammer error msg", "err", err.Error())
+		slave.sendInternalErrorCode()
+		return
 	}
+	slave.wsWrite <- msg
+}
 
-	return spammer, nil
+// subscribe replaces the filter of the metrics sent to the coordinator
+func (slave *Slave) subscribe(payload []byte) error {
+	sub := spam.Subscription{}
+	if err := json.Unmarshal(payload, &sub); err != nil {
+		return err
+	}
+	filter, err := spam.NewMetricFilter(sub)
+	if err != nil {
+		return err
+	}
+	slave.muFilter.Lock()
+	slave.metricFilter = filter
+	slave.muFilter.Unlock()
+	return nil
+}
+
+func (slave *Slave) setLogLevel(payload []byte) error {
+	msg := LogLevelMsg{}
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return err
+	}
+	return utilities.SetLogLevel(msg.Component, msg.Level)
+}
+
+// sendError sends a rejected request of the coordinator with the given msg type
+func (slave *Slave) sendError(msgType api.MsgType, err error) {
+	msg, err := api.NewSlaveMsg(msgType, SpammerErrorMsg{Error: err.Error()})
+	if err != nil {
+		slave.logger.Warn("unable to construct error msg", "type", msgType, "err", err.Error())
+		slave.sendInternalErrorCode()
+		return
+	}
+	slave.wsWrite <- msg
+}
+
+func (slave *Slave) sendEvent(event spam.Event) {
+	msg, err := api.NewSlaveMsg(SLAVE_EVENT, event)
+	if err != nil {
+		slave.logger.Warn("unable to construct event msg", "err", err.Error())
+		return
+	}
+	slave.wsWrite <- msg
 }
@@ -9,6 +9,13 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "bench":
+			os.Exit(bench(os.Args[2:]))
+		}
+	}
+
 	srv := server.Server{}
 
 	sigs := make(chan os.Signal, 1)
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/iota-tangle-io/spamalot-slave/backend/pow"
+	"github.com/iota-tangle-io/spamalot-slave/backend/spam"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// bench runs the PoW benchmark and prints its result as JSON, returns the exit code.
+//
+//	slave bench -impls PowGo,PowC -mwms 9,14 -threads 1,4 -seconds 10
+func bench(args []string) int {
+	defaults := pow.DefaultBenchmarkConfig()
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	impls := flags.String("impls", "", "comma separated PoW implementations, all available if empty")
+	mwms := flags.String("mwms", joinInts(defaults.MWMs), "comma separated MWM values")
+	threads := flags.String("threads", joinInts(defaults.Threads), "comma separated thread counts")
+	seconds := flags.Int("seconds", defaults.Seconds, "seconds to measure each combination")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	config := pow.BenchmarkConfig{Seconds: *seconds}
+	if *impls != "" {
+		config.Impls = strings.Split(*impls, ",")
+	}
+	var err error
+	if config.MWMs, err = splitInts(*mwms); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid mwms: %v\n", err)
+		return 2
+	}
+	if config.Threads, err = splitInts(*threads); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid threads: %v\n", err)
+		return 2
+	}
+
+	if err := config.Validate(spam.MinMWM, spam.MaxMWM); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid benchmark: %v\n", err)
+		return 2
+	}
+
+	fmt.Fprintf(os.Stderr, "benchmarking PoW implementations %v...\n", pow.Available())
+	benchResult, err := pow.Benchmark(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to run benchmark: %v\n", err)
+		return 1
+	}
+	result, err := json.MarshalIndent(benchResult, "", "   ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to marshal benchmark result: %v\n", err)
+		return 1
+	}
+	fmt.Println(string(result))
+	return 0
+}
+
+func splitInts(s string) ([]int, error) {
+	var ints []int
+	for _, part := range strings.Split(s, ",") {
+		i, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		ints = append(ints, i)
+	}
+	return ints, nil
+}
+
+func joinInts(ints []int) string {
+	parts := make([]string, len(ints))
+	for i, n := range ints {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"github.com/iota-tangle-io/iota-spamalot.go"
+	"github.com/iota-tangle-io/spamalot-slave/backend/spam"
+	"time"
+)
+
+const (
+	// metrics older than this aren't replayed
+	backlogMaxAge = time.Duration(10) * time.Minute
+	// summaries are emitted once per second, so that the max age fits
+	backlogSummaries = 600
+	// transactions kept for replay, bursts of transactions don't push out the summaries
+	backlogTxs = 2000
+)
+
+// BacklogEntry is a recent metric replayed to new listeners.
+type BacklogEntry struct {
+	ID     uint64
+	TS     time.Time
+	Metric spam.Metric
+}
+
+// backlog keeps the most recent summaries and transactions in separate rings
+type backlog struct {
+	summaries *ring
+	txs       *ring
+	nextID    uint64
+}
+
+// ids start at the creation time so that they keep increasing across restarts of the slave
+func newBacklog() *backlog {
+	return &backlog{
+		summaries: newRing(backlogSummaries),
+		txs:       newRing(backlogTxs),
+		nextID:    uint64(time.Now().UnixNano()),
+	}
+}
+
+func (b *backlog) push(metric spam.Metric, ts time.Time) BacklogEntry {
+	b.nextID++
+	entry := BacklogEntry{ID: b.nextID, TS: ts, Metric: metric}
+	if metric.Kind == spamalot.SUMMARY {
+		b.summaries.push(entry)
+	} else {
+		b.txs.push(entry)
+	}
+	return entry
+}
+
+// since returns the entries younger than the max age with an id greater than the given one, oldest first
+func (b *backlog) since(id uint64, now time.Time) []BacklogEntry {
+	summaries, txs := b.summaries.since(id, now), b.txs.since(id, now)
+	entries := make([]BacklogEntry, 0, len(summaries)+len(txs))
+	for len(summaries) > 0 && len(txs) > 0 {
+		if summaries[0].ID < txs[0].ID {
+			entries, summaries = append(entries, summaries[0]), summaries[1:]
+		} else {
+			entries, txs = append(entries, txs[0]), txs[1:]
+		}
+	}
+	entries = append(entries, summaries...)
+	return append(entries, txs...)
+}
+
+// ring is a ring buffer of backlog entries
+type ring struct {
+	entries []BacklogEntry
+	// index of the oldest entry
+	start int
+	count int
+}
+
+func newRing(size int) *ring {
+	return &ring{entries: make([]BacklogEntry, size)}
+}
+
+func (r *ring) push(entry BacklogEntry) {
+	if r.count < len(r.entries) {
+		r.entries[(r.start+r.count)%len(r.entries)] = entry
+		r.count++
+		return
+	}
+	r.entries[r.start] = entry
+	r.start = (r.start + 1) % len(r.entries)
+}
+
+func (r *ring) since(id uint64, now time.Time) []BacklogEntry {
+	entries := make([]BacklogEntry, 0, r.count)
+	for i := 0; i < r.count; i++ {
+		entry := r.entries[(r.start+i)%len(r.entries)]
+		if entry.ID <= id || now.Sub(entry.TS) > backlogMaxAge {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iota-tangle-io/iota-spamalot.go"
+	"github.com/iota-tangle-io/spamalot-slave/backend/spam"
+)
+
+func metricOfKind(kind spamalot.MetricType) spam.Metric {
+	return spam.Metric{Metric: spamalot.Metric{Kind: kind}}
+}
+
+func TestBacklogKeepsSummariesDuringTxBursts(t *testing.T) {
+	b := newBacklog()
+	now := time.Now()
+
+	summary := b.push(metricOfKind(spamalot.SUMMARY), now)
+	for i := 0; i < backlogTxs*2; i++ {
+		b.push(metricOfKind(spamalot.INC_SUCCESSFUL_TX), now)
+	}
+
+	entries := b.since(0, now)
+	if len(entries) != backlogTxs+1 {
+		t.Fatalf("expected %d entries, got %d", backlogTxs+1, len(entries))
+	}
+	if entries[0].ID != summary.ID {
+		t.Errorf("expected the summary to be kept and replayed first")
+	}
+	for i := 1; i < len(entries); i++ {
+		if entries[i].ID <= entries[i-1].ID {
+			t.Fatalf("expected the entries ordered by id, got %d after %d", entries[i].ID, entries[i-1].ID)
+		}
+	}
+}
+
+func TestBacklogSince(t *testing.T) {
+	b := newBacklog()
+	now := time.Now()
+
+	b.push(metricOfKind(spamalot.SUMMARY), now.Add(-backlogMaxAge-time.Second))
+	seen := b.push(metricOfKind(spamalot.INC_SUCCESSFUL_TX), now)
+	tx := b.push(metricOfKind(spamalot.INC_SUCCESSFUL_TX), now)
+	summary := b.push(metricOfKind(spamalot.SUMMARY), now)
+
+	entries := b.since(seen.ID, now)
+	if len(entries) != 2 || entries[0].ID != tx.ID || entries[1].ID != summary.ID {
+		t.Errorf("expected only the entries after %d within the max age, got %v", seen.ID, entries)
+	}
+}
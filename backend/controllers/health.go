@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"github.com/CWarner818/giota"
+	"github.com/iota-tangle-io/spamalot-coo/api"
+	slaveapi "github.com/iota-tangle-io/spamalot-slave/api"
+	"github.com/iota-tangle-io/spamalot-slave/backend/models"
+	"github.com/iota-tangle-io/spamalot-slave/backend/pow"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// how long the result of a node probe is reused
+	nodeProbeCacheTime = time.Duration(10) * time.Second
+	nodeProbeTimeout   = time.Duration(5) * time.Second
+)
+
+// LivenessMsg tells that the slave is alive, it is answered without locks or network calls.
+type LivenessMsg struct {
+	Alive  bool   `json:"alive"`
+	Uptime string `json:"uptime"`
+}
+
+// HealthMsg describes the health of the slave and whether it is ready to spam.
+type HealthMsg struct {
+	Ready       bool               `json:"ready"`
+	Coordinator slaveapi.LinkState `json:"coordinator"`
+	Spammer     SpammerHealth      `json:"spammer"`
+	Node        NodeHealth         `json:"node"`
+	PoW         PoWHealth          `json:"pow"`
+	// why the slave isn't ready, empty if it is
+	Problems []string `json:"problems"`
+}
+
+type SpammerHealth struct {
+	// the coordinator's spammer if the slave is linked to one, the dashboard's otherwise
+	Name    string `json:"name"`
+	Running bool   `json:"running"`
+	Desired bool   `json:"desired"`
+}
+
+type NodeHealth struct {
+	Address string `json:"address"`
+	// a dry-run without a node doesn't need one
+	Required  bool      `json:"required"`
+	Reachable bool      `json:"reachable"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type PoWHealth struct {
+	// local PoW implementations available on this host
+	Available []string `json:"available"`
+	// whether the spammer needs local PoW
+	Required bool   `json:"required"`
+	Source   string `json:"source"`
+}
+
+type HealthCtrl struct {
+	Spammer *SpammerCtrl    `inject:""`
+	Slave   *slaveapi.Slave `inject:""`
+
+	started time.Time
+	muProbe sync.Mutex
+	probe   NodeHealth
+}
+
+func (ctrl *HealthCtrl) Init() error {
+	ctrl.started = time.Now()
+	return nil
+}
+
+// Liveness reports that the slave is alive.
+func (ctrl *HealthCtrl) Liveness() *LivenessMsg {
+	return &LivenessMsg{Alive: true, Uptime: time.Since(ctrl.started).String()}
+}
+
+// Health checks the coordinator link, the spammer in charge, its node and PoW.
+func (ctrl *HealthCtrl) Health() *HealthMsg {
+	msg := &HealthMsg{Problems: []string{}}
+
+	msg.Coordinator = ctrl.Slave.Link()
+	if msg.Coordinator.Enabled && !msg.Coordinator.Connected {
+		msg.Problems = append(msg.Problems, "not connected to the coordinator")
+	}
+
+	config, spammer, powSource, ok := ctrl.spammer(msg.Coordinator.Enabled)
+	if !ok {
+		msg.Problems = append(msg.Problems, "no spammer configured by the coordinator yet")
+		return msg
+	}
+	msg.Spammer = spammer
+	if spammer.Desired && !spammer.Running {
+		msg.Problems = append(msg.Problems, "spammer should be running but isn't")
+	}
+
+	if config.NodeAddress != "" {
+		msg.Node = ctrl.probeNode(config.NodeAddress)
+	}
+	msg.Node.Required = !config.DryRun || config.NodeAddress != ""
+	if msg.Node.Required && !msg.Node.Reachable {
+		msg.Problems = append(msg.Problems, "node is not reachable")
+	}
+
+	msg.PoW = PoWHealth{
+		Available: pow.Available(),
+		Required:  config.PoWMode == api.POW_LOCAL || config.HybridPoW || config.DryRun,
+		Source:    powSource,
+	}
+	if msg.PoW.Required && len(msg.PoW.Available) == 0 {
+		msg.Problems = append(msg.Problems, "no local PoW implementation available")
+	}
+
+	msg.Ready = len(msg.Problems) == 0
+	return msg
+}
+
+// spammer returns the config and state of the coordinator's spammer if the slave is linked to one,
+// of the dashboard's otherwise. returns false if the coordinator didn't configure a spammer yet.
+func (ctrl *HealthCtrl) spammer(linked bool) (models.SpammerConfig, SpammerHealth, string, bool) {
+	if !linked {
+		state := ctrl.Spammer.State()
+		health := SpammerHealth{Name: DashboardSpammer, Running: state.Running, Desired: state.Desired}
+		return ctrl.Spammer.Config(), health, state.PoWSource, true
+	}
+
+	spammer, config := ctrl.Slave.Spammer()
+	if spammer == nil || config == nil {
+		return models.SpammerConfig{}, SpammerHealth{}, "", false
+	}
+	health := SpammerHealth{Name: spammer.Name(), Running: spammer.IsRunning(), Desired: spammer.Desired()}
+	return *config, health, spammer.PoWSource(), true
+}
+
+// probeNode asks the node for its info, results are cached shortly to not flood the node with probes
+func (ctrl *HealthCtrl) probeNode(address string) NodeHealth {
+	ctrl.muProbe.Lock()
+	defer ctrl.muProbe.Unlock()
+	if ctrl.probe.Address == address && time.Since(ctrl.probe.CheckedAt) < nodeProbeCacheTime {
+		return ctrl.probe
+	}
+
+	probe := NodeHealth{Address: address, CheckedAt: time.Now()}
+	client := &http.Client{Timeout: nodeProbeTimeout}
+	if _, err := giota.NewAPI(address, client).GetNodeInfo(); err != nil {
+		probe.Error = err.Error()
+	} else {
+		probe.Reachable = true
+	}
+	ctrl.probe = probe
+	return probe
+}
@@ -1,8 +1,17 @@
 package controllers
 
 import (
-	"github.com/iota-tangle-io/iota-spamalot.go"
 	"github.com/CWarner818/giota"
+	"github.com/iota-tangle-io/iota-spamalot.go"
+	"github.com/iota-tangle-io/spamalot-coo/api"
+	"github.com/iota-tangle-io/spamalot-slave/backend/history"
+	"github.com/iota-tangle-io/spamalot-slave/backend/hub"
+	"github.com/iota-tangle-io/spamalot-slave/backend/models"
+	"github.com/iota-tangle-io/spamalot-slave/backend/spam"
+	"github.com/iota-tangle-io/spamalot-slave/backend/txlog"
+	"github.com/iota-tangle-io/spamalot-slave/backend/utilities"
+	"github.com/pkg/errors"
+	"gopkg.in/inconshreveable/log15.v2"
 	"sync"
 	"time"
 )
@@ -10,109 +19,261 @@ import (
 const NirvanaAddress = "999999999999999999999999999999999999999999999999999999999999999999999999999999999"
 const DefaultMessage = "GOSPAMMER9SPAMALOT"
 const DefaultTag = "999SPAMALOT"
+const DefaultNode = "http://nodes.iota.fm:80"
+
+// DashboardSpammer is the name of the dashboard spammer in metrics and the history.
+const DashboardSpammer = "dashboard"
+
+// messages buffered per listener and the number of messages in a row a listener may miss before it is removed
+const listenerBufferSize = 256
+const listenerMaxDrops = 256
+
+var ErrInvalidRate = errors.New("target rate and burst must not be negative")
 
 type StatusMsg struct {
 	Running bool `json:"running"`
+	// whether the spammer should be running, it is restarted if it exited on its own
+	Desired bool `json:"desired"`
+	DryRun  bool `json:"dry_run"`
+	// zero means unlimited
+	TargetTPS float64 `json:"target_tps"`
+	Burst     int     `json:"burst"`
+	// active PoW implementation, empty for remote PoW
+	PoW       string `json:"pow"`
+	PoWSource string `json:"pow_source"`
+	// why the spammer stopped on its own, empty if it didn't
+	StopReason string `json:"stop_reason"`
 }
 
 type SpammerCtrl struct {
-	spammer *spamalot.Spammer
-	metrics chan spamalot.Metric
+	History *history.Store `inject:""`
+	TxLog   *txlog.Log     `inject:""`
+
+	spammer *spam.Spammer
+	config  *models.SpammerConfig
+	metrics chan spam.Metric
+	logger  log15.Logger
 
 	// synchronise access to spammer for now
 	mu sync.Mutex
 
-	muListeners    sync.Mutex
-	listeners      map[int]chan interface{}
-	nextListenerID int
+	listeners *hub.Hub
+	// guards the backlog and orders its updates with new listeners
+	muBacklog sync.Mutex
+	// recent summaries and transactions
+	backlog *backlog
+}
+
+// DefaultSpammerConfig returns the configuration the spammer is initialised with.
+func DefaultSpammerConfig() *models.SpammerConfig {
+	config := &models.SpammerConfig{}
+	config.MWM = 14
+	config.Depth = giota.Depth
+	config.DestAddress = NirvanaAddress
+	config.Tag = DefaultTag
+	config.Message = DefaultMessage
+	config.SecurityLvl = 2
+	config.NodeAddress = DefaultNode
+	config.PoWMode = api.POW_REMOTE
+	return config
 }
 
 func (ctrl *SpammerCtrl) Init() error {
-	ctrl.metrics = make(chan spamalot.Metric)
-	ctrl.listeners = map[int]chan interface{}{}
-
-	spammer, err := spamalot.New(
-		spamalot.WithMWM(int64(14)),
-		spamalot.WithDepth(giota.Depth),
-		spamalot.ToAddress(NirvanaAddress),
-		spamalot.WithTag(DefaultTag),
-		spamalot.WithMessage(DefaultMessage),
-		spamalot.WithSecurityLevel(spamalot.SecurityLevel(2)),
-		spamalot.FilterTrunk(false),
-		spamalot.FilterBranch(false),
-		spamalot.FilterMilestone(false),
-		spamalot.WithMetricsRelay(ctrl.metrics),
-	)
+	logger, err := utilities.GetLogger("spammer")
 	if err != nil {
 		return err
 	}
+	ctrl.logger = logger
+	ctrl.metrics = make(chan spam.Metric)
+	ctrl.listeners = hub.New("dashboard", listenerBufferSize, listenerMaxDrops)
+	ctrl.backlog = newBacklog()
 
-	// configure PoW
-	_, pow := giota.GetBestPoW()
-	spammer.UpdateSettings(spamalot.WithPoW(pow))
-	spammer.UpdateSettings(spamalot.WithNode("http://nodes.iota.fm:80", true))
+	ctrl.config = DefaultSpammerConfig()
+	spammer, err := ctrl.newSpammer(ctrl.config)
+	if err != nil {
+		return err
+	}
 	ctrl.spammer = spammer
 	go ctrl.readMetrics()
 
 	return nil
 }
 
+func (ctrl *SpammerCtrl) newSpammer(config *models.SpammerConfig) (*spam.Spammer, error) {
+	spammer, err := spam.New(config, ctrl.metrics, ctrl.logger)
+	if err != nil {
+		return nil, err
+	}
+	spammer.SetName(DashboardSpammer)
+	spammer.SetTxLog(ctrl.TxLog)
+	spammer.OnAutoStop = ctrl.onAutoStop
+	spammer.OnEvent = ctrl.onEvent
+	return spammer, nil
+}
+
+// onEvent passes events of the spammer on to the listeners
+func (ctrl *SpammerCtrl) onEvent(event spam.Event) {
+	ctrl.broadcast(&event)
+}
+
+// onAutoStop informs the listeners about the spammer having stopped on its own
+func (ctrl *SpammerCtrl) onAutoStop(reason string) {
+	ctrl.broadcast(ctrl.State())
+}
+
 func (ctrl *SpammerCtrl) readMetrics() {
 	for metric := range ctrl.metrics {
-		ctrl.muListeners.Lock()
-		for id, channel := range ctrl.listeners {
-			select {
-			case channel <- metric:
-				// timeout
-			case <-time.After(time.Duration(1) * time.Second):
-				// auto remove if not writable within 1 second
-				delete(ctrl.listeners, id)
-				close(channel) // unwind listener
-			}
+		switch metric.Kind {
+		case spamalot.SUMMARY:
+			ctrl.record(metric)
+			ctrl.broadcastMetric(metric)
+		case spamalot.INC_SUCCESSFUL_TX:
+			ctrl.broadcastMetric(metric)
+		default:
+			ctrl.broadcast(metric)
 		}
-		ctrl.muListeners.Unlock()
 	}
 }
 
+// broadcastMetric keeps the given metric in the backlog and sends its backlog entry to all listeners
+func (ctrl *SpammerCtrl) broadcastMetric(metric spam.Metric) {
+	ctrl.muBacklog.Lock()
+	defer ctrl.muBacklog.Unlock()
+	ctrl.listeners.Publish(ctrl.backlog.push(metric, time.Now()))
+}
+
+// record persists the given summary in the history
+func (ctrl *SpammerCtrl) record(metric spam.Metric) {
+	point := history.Point{TS: time.Now(), Spammer: DashboardSpammer}
+	if err := spam.DecodeData(metric.Data, &point.Summary); err != nil {
+		ctrl.logger.Warn("unable to decode summary", "err", err.Error())
+		return
+	}
+	if err := ctrl.History.Append(point); err != nil {
+		ctrl.logger.Warn("unable to persist summary", "err", err.Error())
+	}
+}
+
+// broadcast sends the given metric, state or event to all listeners
+func (ctrl *SpammerCtrl) broadcast(msg interface{}) {
+	ctrl.listeners.Publish(msg)
+}
+
+// Start starts the spammer and blocks until it is running or failed to start.
+// the lock isn't held while waiting, so that the state can be read meanwhile.
 func (ctrl *SpammerCtrl) Start() error {
 	ctrl.mu.Lock()
-	defer ctrl.mu.Unlock()
-	if ctrl.spammer.IsRunning() {
-		return nil
-	}
-	go ctrl.spammer.Start()
-	<-time.After(time.Duration(1) * time.Second)
-	return nil
+	spammer := ctrl.spammer
+	ctrl.mu.Unlock()
+	return spammer.StartWait(spam.DefaultStartTimeout)
 }
 
+// Stop stops the spammer, a restart pending after it exited on its own is cancelled as well.
 func (ctrl *SpammerCtrl) Stop() error {
 	ctrl.mu.Lock()
 	defer ctrl.mu.Unlock()
-	if !ctrl.spammer.IsRunning() {
-		return nil
-	}
-	ctrl.spammer.Stop()
-	return nil
+	return ctrl.spammer.Stop()
 }
 
 func (ctrl *SpammerCtrl) State() *StatusMsg {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
 	msg := &StatusMsg{}
 	msg.Running = ctrl.spammer.IsRunning()
+	msg.Desired = ctrl.spammer.Desired()
+	msg.DryRun = ctrl.config.DryRun
+	msg.TargetTPS = ctrl.config.TargetTPS
+	msg.Burst = ctrl.config.Burst
+	msg.PoW = ctrl.spammer.PoW()
+	msg.PoWSource = ctrl.spammer.PoWSource()
+	msg.StopReason = ctrl.spammer.StopReason()
 	return msg
 }
 
-func (ctrl *SpammerCtrl) AddMetricListener(channel chan interface{}) int {
-	ctrl.muListeners.Lock()
-	defer ctrl.muListeners.Unlock()
-	ctrl.nextListenerID++
-	ctrl.listeners[ctrl.nextListenerID] = channel
-	return ctrl.nextListenerID
+// SetRate changes the target rate of the spammer without restarting it.
+func (ctrl *SpammerCtrl) SetRate(tps float64, burst int) error {
+	if tps < 0 || burst < 0 {
+		return ErrInvalidRate
+	}
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	ctrl.spammer.SetRate(tps, burst)
+	return nil
+}
+
+// Config returns a copy of the current spammer configuration.
+func (ctrl *SpammerCtrl) Config() models.SpammerConfig {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	return *ctrl.config
+}
+
+// UpdateConfig replaces the spammer with one using the given configuration.
+// the new spammer is started if the previous one was running. if only runtime settings
+// changed, they are applied to the current spammer instead.
+// returns spam.ValidationErrors if the configuration is invalid.
+func (ctrl *SpammerCtrl) UpdateConfig(config *models.SpammerConfig) error {
+	if err := spam.Validate(config); err != nil {
+		return err
+	}
+
+	spammer, wasRunning, err := ctrl.replaceSpammer(config)
+	if spammer != nil && wasRunning {
+		if startErr := spammer.StartWait(spam.DefaultStartTimeout); startErr != nil {
+			if err != nil {
+				ctrl.logger.Warn("unable to restart spammer with previous config", "err", startErr.Error())
+				return err
+			}
+			return startErr
+		}
+	}
+	return err
 }
 
-func (ctrl *SpammerCtrl) RemoveMetricListener(id int) {
-	ctrl.muListeners.Lock()
-	defer ctrl.muListeners.Unlock()
-	if _, ok := ctrl.listeners[id]; ok {
-		delete(ctrl.listeners, id)
+// replaceSpammer swaps the spammer for one using the given configuration and returns the spammer
+// to start if the previous one was running. on error the spammer falls back to the previous configuration.
+// the spammer is started by the caller so that the lock isn't held while it is starting.
+func (ctrl *SpammerCtrl) replaceSpammer(config *models.SpammerConfig) (*spam.Spammer, bool, error) {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+
+	if config.EqualsIgnoringRuntime(*ctrl.config) {
+		ctrl.spammer.UpdateRuntime(config)
+		return nil, false, nil
+	}
+
+	// a spammer waiting to be restarted by its supervisor counts as running
+	wasRunning := ctrl.spammer.Desired()
+	if err := ctrl.spammer.Close(); err != nil {
+		return nil, false, err
+	}
+
+	spammer, err := ctrl.newSpammer(config)
+	if err != nil {
+		// fall back to the previous configuration
+		previous, prevErr := ctrl.newSpammer(ctrl.config)
+		if prevErr != nil {
+			// the closed spammer is kept, closing it again on the next update is a no-op
+			return nil, false, prevErr
+		}
+		ctrl.spammer = previous
+		return previous, wasRunning, err
 	}
+	ctrl.spammer = spammer
+	ctrl.config = config
+	return spammer, wasRunning, nil
+}
+
+// AddMetricListener adds a listener and returns the recent metrics after the backlog entry with the given id,
+// zero returns all recent metrics. no metric is both in the backlog and sent to the listener.
+// summaries and transactions are sent as BacklogEntry, other metrics as spam.Metric.
+// the channel of the listener is closed if it can't keep up with the metrics.
+func (ctrl *SpammerCtrl) AddMetricListener(since uint64) (*hub.Subscriber, []BacklogEntry) {
+	ctrl.muBacklog.Lock()
+	defer ctrl.muBacklog.Unlock()
+	return ctrl.listeners.Subscribe(), ctrl.backlog.since(since, time.Now())
+}
+
+func (ctrl *SpammerCtrl) RemoveMetricListener(listener *hub.Subscriber) {
+	ctrl.listeners.Unsubscribe(listener)
 }
@@ -0,0 +1,311 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iota-tangle-io/spamalot-slave/backend/ratelimit"
+	"github.com/pkg/errors"
+)
+
+const nineHash = "999999999999999999999999999999999999999999999999999999999999999999999999999999999"
+const txTrytesSize = 2673
+
+var ErrNoUpstream = errors.New("gateway has no upstream node")
+
+// an Option configures the gateway
+type Option func(gw *Gateway)
+
+// DryRun makes the gateway acknowledge broadcastTransactions and storeTransactions
+// without forwarding them to the upstream node.
+func DryRun(dryRun bool) Option {
+	return func(gw *Gateway) {
+		gw.dryRun = dryRun
+	}
+}
+
+// RateLimit makes the gateway hold back getTransactionsToApprove calls according to the given limiter.
+// as every transaction starts with tip selection, this limits the rate at which the spammer
+// creates transactions without wasting any PoW.
+func RateLimit(limiter *ratelimit.Limiter) Option {
+	return func(gw *Gateway) {
+		gw.limiter = limiter
+	}
+}
+
+// OnAttachFailure makes the gateway report failed attachToTangle calls to the given function.
+// a call fails if the node refuses the command or doesn't answer within the given timeout.
+func OnAttachFailure(onFailure func(err error), timeout time.Duration) Option {
+	return func(gw *Gateway) {
+		gw.onAttachFailure = onFailure
+		gw.attachTimeout = timeout
+	}
+}
+
+// Observe makes the gateway report the duration of each command relayed to the node.
+func Observe(observe func(command string, duration time.Duration)) Option {
+	return func(gw *Gateway) {
+		gw.observe = observe
+	}
+}
+
+// Gateway is a local IRI API endpoint which sits between a spammer and its node.
+// the spammer is pointed to the gateway which relays commands to the upstream node,
+// giving the slave a hook into every call the spammer makes.
+// without an upstream node the gateway answers with synthetic tips (only valid in dry-run).
+type Gateway struct {
+	upstream string
+	dryRun   bool
+	limiter  *ratelimit.Limiter
+	client   *http.Client
+	listener net.Listener
+	server   *http.Server
+
+	onAttachFailure func(err error)
+	attachTimeout   time.Duration
+
+	observe func(command string, duration time.Duration)
+
+	muReady sync.Mutex
+	ready   chan struct{}
+}
+
+// statusRecorder remembers the status code of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(statusCode int) {
+	rec.status = statusCode
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+type command struct {
+	Command string   `json:"command"`
+	Hashes  []string `json:"hashes"`
+}
+
+// New creates a new gateway relaying to the given upstream node and starts listening on a local port.
+func New(upstream string, options ...Option) (*Gateway, error) {
+	gw := &Gateway{
+		upstream: strings.TrimRight(upstream, "/"),
+		client:   &http.Client{Timeout: time.Duration(2) * time.Minute},
+	}
+	for _, option := range options {
+		option(gw)
+	}
+
+	if gw.upstream == "" && !gw.dryRun {
+		return nil, ErrNoUpstream
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open gateway listener")
+	}
+	gw.listener = listener
+	gw.server = &http.Server{Handler: gw}
+	go gw.server.Serve(listener)
+	return gw, nil
+}
+
+// Address returns the URL under which the gateway is reachable by the spammer.
+func (gw *Gateway) Address() string {
+	return "http://" + gw.listener.Addr().String()
+}
+
+// Upstream returns the URL of the node the gateway relays to.
+func (gw *Gateway) Upstream() string {
+	return gw.upstream
+}
+
+// Close stops the gateway.
+func (gw *Gateway) Close() error {
+	return gw.server.Close()
+}
+
+// Ready returns a channel which is closed once the spammer got its next successful answer.
+func (gw *Gateway) Ready() <-chan struct{} {
+	gw.muReady.Lock()
+	defer gw.muReady.Unlock()
+	if gw.ready == nil {
+		gw.ready = make(chan struct{})
+	}
+	return gw.ready
+}
+
+func (gw *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := &statusRecorder{ResponseWriter: w}
+	gw.serve(rec, r)
+	if rec.status != http.StatusOK {
+		return
+	}
+
+	gw.muReady.Lock()
+	if gw.ready != nil {
+		close(gw.ready)
+		gw.ready = nil
+	}
+	gw.muReady.Unlock()
+}
+
+func (gw *Gateway) serve(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cmd := &command{}
+	if err := json.Unmarshal(body, cmd); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	switch cmd.Command {
+	case "getTransactionsToApprove":
+		if gw.limiter != nil && !gw.limiter.Wait(r.Context().Done()) {
+			return
+		}
+	case "broadcastTransactions", "storeTransactions":
+		if gw.dryRun {
+			writeJSON(w, http.StatusOK, map[string]interface{}{"duration": 0})
+			return
+		}
+	case "attachToTangle":
+		if gw.onAttachFailure != nil {
+			start := time.Now()
+			gw.forwardAttach(w, r, body)
+			if gw.observe != nil {
+				gw.observe(cmd.Command, time.Since(start))
+			}
+			return
+		}
+	}
+
+	if gw.upstream == "" {
+		gw.synthesize(w, cmd)
+		return
+	}
+
+	start := time.Now()
+	gw.forward(w, r, body)
+	if gw.observe != nil {
+		gw.observe(cmd.Command, time.Since(start))
+	}
+}
+
+// forward relays the request to the upstream node and copies back its response
+func (gw *Gateway) forward(w http.ResponseWriter, r *http.Request, body []byte) {
+	statusCode, resBody, err := gw.relay(r.Context(), r.Header, body)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(resBody)
+}
+
+// forwardAttach relays an attachToTangle call and reports a refusal or timeout of the node
+func (gw *Gateway) forwardAttach(w http.ResponseWriter, r *http.Request, body []byte) {
+	ctx, cancel := context.WithTimeout(r.Context(), gw.attachTimeout)
+	defer cancel()
+
+	// failures are reported asynchronously as the spammer might hold its settings lock while waiting for us
+	statusCode, resBody, err := gw.relay(ctx, r.Header, body)
+	if err != nil {
+		go gw.onAttachFailure(err)
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	if refused(statusCode, resBody) {
+		go gw.onAttachFailure(errors.Errorf("node refused attachToTangle: %s", resBody))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(resBody)
+}
+
+func (gw *Gateway) relay(ctx context.Context, header http.Header, body []byte) (int, []byte, error) {
+	req, err := http.NewRequest(http.MethodPost, gw.upstream, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header = header
+
+	res, err := gw.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return res.StatusCode, resBody, nil
+}
+
+// refused reports whether the node's response says the command isn't available on it
+func refused(statusCode int, body []byte) bool {
+	if statusCode == http.StatusOK {
+		return false
+	}
+	if statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden {
+		return true
+	}
+	msg := strings.ToLower(string(body))
+	return strings.Contains(msg, "not available") || strings.Contains(msg, "not allowed")
+}
+
+// synthesize answers the commands used by the spammer without any node
+func (gw *Gateway) synthesize(w http.ResponseWriter, cmd *command) {
+	switch cmd.Command {
+	case "getTransactionsToApprove":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"trunkTransaction": nineHash, "branchTransaction": nineHash, "duration": 0,
+		})
+	case "getTrytes":
+		trytes := make([]string, len(cmd.Hashes))
+		for i := range trytes {
+			trytes[i] = strings.Repeat("9", txTrytesSize)
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"trytes": trytes, "duration": 0})
+	case "getNodeInfo":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"appName": "spamalot-gateway", "latestMilestone": nineHash, "latestSolidSubtangleMilestone": nineHash,
+			"duration": 0,
+		})
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "command " + cmd.Command + " is not available without a node",
+		})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(obj)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, err error) {
+	writeJSON(w, statusCode, map[string]interface{}{"error": err.Error()})
+}
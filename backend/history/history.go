@@ -0,0 +1,259 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iota-tangle-io/spamalot-slave/backend/spam"
+)
+
+const filePrefix = "summaries-"
+const fileSuffix = ".jsonl"
+const dayLayout = "2006-01-02"
+
+// Point is a spammer summary at a point in time.
+type Point struct {
+	TS      time.Time    `json:"ts"`
+	Spammer string       `json:"spammer"`
+	Summary spam.Summary `json:"summary"`
+}
+
+// Bucket aggregates the points within one step of a queried series.
+type Bucket struct {
+	TS           time.Time `json:"ts"`
+	TPS          float64   `json:"tps"`
+	ErrorRate    float64   `json:"error_rate"`
+	TXsSucceeded int       `json:"txs_succeeded"`
+	TXsFailed    int       `json:"txs_failed"`
+	Samples      int       `json:"samples"`
+}
+
+// Store is an append-only store of summaries with one JSON lines file per day.
+// files older than the retention are deleted when a new day begins.
+type Store struct {
+	dir       string
+	retention time.Duration
+
+	mu   sync.Mutex
+	day  string
+	file *os.File
+}
+
+// Open opens the store in the given directory, creating it if needed.
+func Open(dir string, retention time.Duration) (*Store, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	store := &Store{dir: dir, retention: retention}
+	if err := store.prune(time.Now()); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (store *Store) path(day string) string {
+	return filepath.Join(store.dir, filePrefix+day+fileSuffix)
+}
+
+// Append adds the given point to the file of its day.
+func (store *Store) Append(point Point) error {
+	line, err := json.Marshal(point)
+	if err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	day := point.TS.UTC().Format(dayLayout)
+	if store.file == nil || day != store.day {
+		if store.file != nil {
+			store.file.Close()
+			store.file = nil
+		}
+		if err := store.prune(point.TS); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(store.path(day), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0666)
+		if err != nil {
+			return err
+		}
+		store.file, store.day = file, day
+	}
+
+	_, err = store.file.Write(append(line, '\n'))
+	return err
+}
+
+// prune deletes the files of days which are completely outside of the retention
+func (store *Store) prune(now time.Time) error {
+	days, err := store.days()
+	if err != nil {
+		return err
+	}
+	oldest := now.Add(-store.retention).UTC().Format(dayLayout)
+	for _, day := range days {
+		if day < oldest {
+			if err := os.Remove(store.path(day)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// days returns the days for which files exist in ascending order
+func (store *Store) days() ([]string, error) {
+	files, err := ioutil.ReadDir(store.dir)
+	if err != nil {
+		return nil, err
+	}
+	var days []string
+	for _, file := range files {
+		name := file.Name()
+		if strings.HasPrefix(name, filePrefix) && strings.HasSuffix(name, fileSuffix) {
+			days = append(days, strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+		}
+	}
+	sort.Strings(days)
+	return days, nil
+}
+
+// Query returns the summaries of the given spammer within [from, to] downsampled into buckets of the given step.
+// rates are averaged and transaction counts summed per bucket, empty buckets are omitted.
+func (store *Store) Query(spammer string, from time.Time, to time.Time, step time.Duration) ([]Bucket, error) {
+	if step <= 0 {
+		return nil, fmt.Errorf("step must be positive")
+	}
+
+	readers, err := store.snapshot(from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, r := range readers {
+			r.Close()
+		}
+	}()
+
+	buckets := map[int64]*Bucket{}
+	for _, r := range readers {
+		if err := scan(r, func(point *Point) {
+			if point.Spammer != spammer || point.TS.Before(from) || point.TS.After(to) {
+				return
+			}
+			index := int64(point.TS.Sub(from) / step)
+			bucket, ok := buckets[index]
+			if !ok {
+				bucket = &Bucket{TS: from.Add(time.Duration(index) * step)}
+				buckets[index] = bucket
+			}
+			bucket.TPS += point.Summary.TPS
+			bucket.ErrorRate += point.Summary.ErrorRate
+			bucket.TXsSucceeded += point.Summary.TXsSucceeded
+			bucket.TXsFailed += point.Summary.TXsFailed
+			bucket.Samples++
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	series := make([]Bucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		bucket.TPS /= float64(bucket.Samples)
+		bucket.ErrorRate /= float64(bucket.Samples)
+		series = append(series, *bucket)
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].TS.Before(series[j].TS) })
+	return series, nil
+}
+
+// snapshotReader reads the file of a day up to its size at the time of the snapshot
+type snapshotReader struct {
+	io.Reader
+	file *os.File
+}
+
+func (r *snapshotReader) Close() error {
+	return r.file.Close()
+}
+
+// snapshot opens the files of the days within [from, to], so that they can be read without holding
+// the lock while appending goes on. the file currently appended to is only read up to its size at the
+// time of the snapshot.
+func (store *Store) snapshot(from time.Time, to time.Time) ([]*snapshotReader, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	days, err := store.days()
+	if err != nil {
+		return nil, err
+	}
+
+	var readers []*snapshotReader
+	closeAll := func() {
+		for _, r := range readers {
+			r.Close()
+		}
+	}
+	firstDay, lastDay := from.UTC().Format(dayLayout), to.UTC().Format(dayLayout)
+	for _, day := range days {
+		if day < firstDay || day > lastDay {
+			continue
+		}
+		file, err := os.Open(store.path(day))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			closeAll()
+			return nil, err
+		}
+		var r io.Reader = file
+		if store.file != nil && day == store.day {
+			info, err := store.file.Stat()
+			if err != nil {
+				file.Close()
+				closeAll()
+				return nil, err
+			}
+			r = io.LimitReader(file, info.Size())
+		}
+		readers = append(readers, &snapshotReader{Reader: r, file: file})
+	}
+	return readers, nil
+}
+
+// scan calls fn for each point read from r, corrupt lines are skipped
+func scan(r io.Reader, fn func(point *Point)) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		point := &Point{}
+		if err := json.Unmarshal(scanner.Bytes(), point); err != nil {
+			continue
+		}
+		fn(point)
+	}
+	return scanner.Err()
+}
+
+// Close closes the file currently appended to.
+func (store *Store) Close() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.file == nil {
+		return nil
+	}
+	err := store.file.Close()
+	store.file = nil
+	return err
+}
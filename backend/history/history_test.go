@@ -0,0 +1,101 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/iota-tangle-io/spamalot-slave/backend/spam"
+)
+
+func TestQuery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := Open(dir, 7*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// one summary per second across midnight, the second minute has no summaries
+	from := time.Now().UTC().Truncate(24 * time.Hour).Add(-time.Minute)
+	for i := 0; i < 180; i++ {
+		if i >= 60 && i < 120 {
+			continue
+		}
+		point := Point{TS: from.Add(time.Duration(i) * time.Second), Spammer: "dashboard",
+			Summary: spam.Summary{TPS: float64(i % 2), ErrorRate: 0.5, TXsSucceeded: 1, TXsFailed: 2}}
+		if err := store.Append(point); err != nil {
+			t.Fatal(err)
+		}
+		point.Spammer = "coordinator"
+		if err := store.Append(point); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buckets, err := store.Query("dashboard", from, from.Add(3*time.Minute), 30*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets) != 4 {
+		t.Fatalf("expected the empty buckets to be omitted, got %d buckets", len(buckets))
+	}
+	expectedTS := []time.Duration{0, 30 * time.Second, 120 * time.Second, 150 * time.Second}
+	for i, bucket := range buckets {
+		if !bucket.TS.Equal(from.Add(expectedTS[i])) {
+			t.Errorf("bucket %d: expected ts %v, got %v", i, from.Add(expectedTS[i]), bucket.TS)
+		}
+		if bucket.Samples != 30 || bucket.TXsSucceeded != 30 || bucket.TXsFailed != 60 {
+			t.Errorf("bucket %d: expected 30 samples summed, got %+v", i, bucket)
+		}
+		if bucket.TPS != 0.5 || bucket.ErrorRate != 0.5 {
+			t.Errorf("bucket %d: expected rates averaged, got %+v", i, bucket)
+		}
+	}
+
+	if _, err := store.Query("dashboard", from, from.Add(time.Minute), 0); err == nil {
+		t.Error("expected a zero step to be rejected")
+	}
+}
+
+// queries read consistent snapshots while points are appended
+func TestQueryWhileAppending(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := Open(dir, 7*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			store.Append(Point{TS: now, Spammer: "dashboard"})
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		if _, err := store.Query("dashboard", now.Add(-time.Minute), now.Add(time.Minute), time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+
+	buckets, err := store.Query("dashboard", now.Add(-time.Minute), now.Add(time.Minute), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets) != 1 || buckets[0].Samples != 1000 {
+		t.Errorf("expected all 1000 points, got %+v", buckets)
+	}
+}
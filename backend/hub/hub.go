@@ -0,0 +1,129 @@
+package hub
+
+import (
+	"github.com/iota-tangle-io/spamalot-slave/backend/prom"
+	"sync"
+)
+
+var (
+	droppedTotal = prom.Default.Counter("spamalot_hub_dropped_total",
+		"Messages dropped because a subscriber couldn't keep up.", "hub")
+	disconnectedTotal = prom.Default.Counter("spamalot_hub_disconnected_total",
+		"Subscribers disconnected for being too slow.", "hub")
+	subscribersGauge = prom.Default.Gauge("spamalot_hub_subscribers",
+		"Current number of subscribers.", "hub")
+)
+
+// Hub fans out messages to subscribers without ever blocking the publisher.
+// each subscriber has a bounded buffer from which the oldest message is dropped when it is full,
+// subscribers which keep dropping are disconnected by closing their channel.
+type Hub struct {
+	name       string
+	bufferSize int
+	maxDrops   int
+
+	mu     sync.Mutex
+	subs   map[int]*Subscriber
+	nextID int
+}
+
+// Subscriber receives the messages published to a hub.
+type Subscriber struct {
+	id int
+	ch chan interface{}
+
+	// guarded by the hub's mutex
+	dropped     uint64
+	consecutive int
+}
+
+// C returns the channel the messages are received from, it is closed when the subscriber is removed.
+func (sub *Subscriber) C() <-chan interface{} {
+	return sub.ch
+}
+
+// New creates a hub with the given buffer size per subscriber which disconnects subscribers
+// after the given number of publishes in a row dropped a message for them.
+func New(name string, bufferSize int, maxDrops int) *Hub {
+	return &Hub{name: name, bufferSize: bufferSize, maxDrops: maxDrops, subs: map[int]*Subscriber{}}
+}
+
+// Subscribe adds a new subscriber.
+func (h *Hub) Subscribe() *Subscriber {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nextID++
+	sub := &Subscriber{id: h.nextID, ch: make(chan interface{}, h.bufferSize)}
+	h.subs[sub.id] = sub
+	subscribersGauge.Set(float64(len(h.subs)), h.name)
+	return sub
+}
+
+// Unsubscribe removes the given subscriber, it is a no-op if it was removed already.
+func (h *Hub) Unsubscribe(sub *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(sub)
+}
+
+// must be called with mu held
+func (h *Hub) remove(sub *Subscriber) {
+	if _, ok := h.subs[sub.id]; !ok {
+		return
+	}
+	delete(h.subs, sub.id)
+	close(sub.ch)
+	subscribersGauge.Set(float64(len(h.subs)), h.name)
+}
+
+// Publish sends the given message to all subscribers.
+func (h *Hub) Publish(msg interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sub := range h.subs {
+		if h.deliver(sub, msg) {
+			sub.consecutive = 0
+			continue
+		}
+		sub.dropped++
+		sub.consecutive++
+		droppedTotal.Inc(h.name)
+		if h.maxDrops > 0 && sub.consecutive >= h.maxDrops {
+			h.remove(sub)
+			disconnectedTotal.Inc(h.name)
+		}
+	}
+}
+
+// deliver puts the message into the buffer of the subscriber, returns false if a message had to be dropped
+func (h *Hub) deliver(sub *Subscriber, msg interface{}) bool {
+	select {
+	case sub.ch <- msg:
+		return true
+	default:
+	}
+	// buffer is full, make room by dropping the oldest message
+	select {
+	case <-sub.ch:
+	default:
+	}
+	select {
+	case sub.ch <- msg:
+	default:
+	}
+	return false
+}
+
+// Dropped returns the number of messages dropped for the given subscriber.
+func (h *Hub) Dropped(sub *Subscriber) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return sub.dropped
+}
+
+// Len returns the number of subscribers.
+func (h *Hub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
@@ -0,0 +1,112 @@
+package hub
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestPublish(t *testing.T) {
+	h := New("test-publish", 4, 0)
+	subs := []*Subscriber{h.Subscribe(), h.Subscribe()}
+
+	h.Publish(1)
+	h.Publish(2)
+	for _, sub := range subs {
+		for _, expected := range []int{1, 2} {
+			if msg := <-sub.C(); msg != expected {
+				t.Errorf("expected %d, got %v", expected, msg)
+			}
+		}
+	}
+}
+
+func TestDropOldest(t *testing.T) {
+	h := New("test-drop-oldest", 3, 0)
+	sub := h.Subscribe()
+
+	for i := 0; i < 5; i++ {
+		h.Publish(i)
+	}
+	if dropped := h.Dropped(sub); dropped != 2 {
+		t.Errorf("expected 2 dropped messages, got %d", dropped)
+	}
+	// the newest messages are kept
+	for _, expected := range []int{2, 3, 4} {
+		if msg := <-sub.C(); msg != expected {
+			t.Errorf("expected %d, got %v", expected, msg)
+		}
+	}
+}
+
+func TestSlowSubscriberDisconnect(t *testing.T) {
+	h := New("test-disconnect", 2, 3)
+	slow := h.Subscribe()
+	fast := h.Subscribe()
+
+	for i := 0; i < 5; i++ {
+		h.Publish(i)
+		// the fast subscriber keeps up and is never dropped from
+		if msg := <-fast.C(); msg != i {
+			t.Fatalf("expected %d, got %v", i, msg)
+		}
+	}
+
+	if h.Len() != 1 {
+		t.Fatalf("expected the slow subscriber to be disconnected, got %d subscribers", h.Len())
+	}
+	// the buffered messages are still delivered before the channel is closed
+	var received []interface{}
+	for msg := range slow.C() {
+		received = append(received, msg)
+	}
+	if len(received) != 2 {
+		t.Errorf("expected the 2 buffered messages, got %v", received)
+	}
+
+	// unsubscribing a disconnected subscriber is a no-op
+	h.Unsubscribe(slow)
+	h.Unsubscribe(fast)
+	if _, ok := <-fast.C(); ok {
+		t.Error("expected the channel of an unsubscribed subscriber to be closed")
+	}
+}
+
+func TestDropsInARowOnly(t *testing.T) {
+	h := New("test-drops-in-a-row", 1, 2)
+	sub := h.Subscribe()
+
+	// a drop followed by a delivery resets the count
+	for i := 0; i < 10; i++ {
+		h.Publish(i)
+		h.Publish(i)
+		<-sub.C()
+	}
+	if h.Len() != 1 {
+		t.Error("expected the subscriber to stay subscribed")
+	}
+}
+
+func BenchmarkPublish(b *testing.B) {
+	h := New("bench", 256, 256)
+	var wg sync.WaitGroup
+	for i := 0; i < 500; i++ {
+		sub := h.Subscribe()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range sub.C() {
+			}
+		}()
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Publish(i)
+	}
+	b.StopTimer()
+
+	for _, sub := range h.subs {
+		h.Unsubscribe(sub)
+	}
+	wg.Wait()
+}
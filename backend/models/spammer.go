@@ -0,0 +1,73 @@
+package models
+
+import (
+	"github.com/iota-tangle-io/spamalot-coo/api"
+	"reflect"
+	"time"
+)
+
+// SpammerConfig is the configuration of a spammer run by the slave.
+// it embeds the coordinator's configuration and adds options only known to the slave.
+// slave options are omitted when empty so that the config hash of a plain
+// coordinator configuration stays the same.
+type SpammerConfig struct {
+	api.SpammerConfig
+
+	// runs tip selection, bundle construction and PoW but never broadcasts/stores transactions
+	DryRun bool `json:"dry_run,omitempty"`
+	// target rate in tx/s, zero means unlimited
+	TargetTPS float64 `json:"target_tps,omitempty"`
+	// amount of transactions which may be issued at once above the target rate
+	Burst int `json:"burst,omitempty"`
+	// name of the giota PoW implementation used for local PoW, empty means the best available
+	PoWImpl string `json:"pow_impl,omitempty"`
+	// destination addresses rotated through instead of DestAddress
+	DestAddresses []string `json:"dest_addresses,omitempty"`
+	// seed to derive DestCount destination addresses from, added to DestAddresses
+	DestSeed  string `json:"dest_seed,omitempty"`
+	DestCount int    `json:"dest_count,omitempty"`
+	// rotation strategy: round-robin (default), random or per-n
+	Rotation string `json:"rotation,omitempty"`
+	// transactions per address with the per-n strategy
+	RotateEvery int `json:"rotate_every,omitempty"`
+	// stop conditions, zero values disable them
+	StopAfterTxs     int        `json:"stop_after_txs,omitempty"`
+	StopAfterSeconds int        `json:"stop_after_seconds,omitempty"`
+	StopAt           *time.Time `json:"stop_at,omitempty"`
+	// stops when the error rate of the summaries stays above StopErrorRate for StopErrorRateSeconds
+	StopErrorRate        float64 `json:"stop_error_rate,omitempty"`
+	StopErrorRateSeconds int     `json:"stop_error_rate_seconds,omitempty"`
+	// adjusts depth and tip filters to the rate of bad tips, nil disables it
+	Adaptive *AdaptiveConfig `json:"adaptive,omitempty"`
+	// with remote PoW, falls back to local PoW while the node refuses or times out on attachToTangle
+	HybridPoW bool `json:"hybrid_pow,omitempty"`
+	// max amount of threads used by local PoW, zero means giota's default
+	PoWThreads int `json:"pow_threads,omitempty"`
+	// GOMAXPROCS of the slave process, zero restores the one at startup
+	MaxProcs int `json:"max_procs,omitempty"`
+	// scheduling niceness of the slave process, zero restores the one at startup
+	Niceness int `json:"niceness,omitempty"`
+}
+
+// AdaptiveConfig bounds the adjustments made to a spammer on bad trunk/branch feedback.
+type AdaptiveConfig struct {
+	MinDepth int `json:"min_depth"`
+	MaxDepth int `json:"max_depth"`
+	// fraction of bad tips per issued transaction above which the depth is raised
+	MaxBadTipRate float64 `json:"max_bad_tip_rate"`
+	// whether trunk and branch filters are enabled while the depth is raised
+	AdjustFilters bool `json:"adjust_filters"`
+}
+
+// EqualsIgnoringRuntime reports whether both configurations only differ in
+// settings which can be changed on a running spammer (target rate and resource limits).
+func (config SpammerConfig) EqualsIgnoringRuntime(other SpammerConfig) bool {
+	config.clearRuntime()
+	other.clearRuntime()
+	return reflect.DeepEqual(config, other)
+}
+
+func (config *SpammerConfig) clearRuntime() {
+	config.TargetTPS, config.Burst = 0, 0
+	config.PoWThreads, config.MaxProcs, config.Niceness = 0, 0, 0
+}
@@ -0,0 +1,226 @@
+package pow
+
+import (
+	"errors"
+	"fmt"
+	"github.com/CWarner818/giota"
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const tryteAlphabet = "9ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+const txTrytesSize = 2673
+
+const (
+	// the duration of a single run is bounded, runs of a benchmark started through the api happen in the background
+	MaxBenchmarkSeconds = 60
+	// max combinations of implementation, mwm and threads measured by one benchmark
+	MaxBenchmarkRuns = 32
+)
+
+// BenchmarkConfig defines which PoW implementations are benchmarked under which settings.
+type BenchmarkConfig struct {
+	// implementations to benchmark, empty means all available
+	Impls   []string `json:"impls"`
+	MWMs    []int    `json:"mwms"`
+	Threads []int    `json:"threads"`
+	// how long each combination of implementation, mwm and threads is measured
+	Seconds int `json:"seconds"`
+}
+
+// DefaultBenchmarkConfig benchmarks all implementations with all cores at the mainnet and testnet MWM.
+func DefaultBenchmarkConfig() BenchmarkConfig {
+	return BenchmarkConfig{MWMs: []int{9, 14}, Threads: []int{runtime.NumCPU()}, Seconds: 10}
+}
+
+// WithDefaults returns the config with the defaults filled in for the empty fields.
+func (config BenchmarkConfig) WithDefaults() BenchmarkConfig {
+	defaults := DefaultBenchmarkConfig()
+	if len(config.Impls) == 0 {
+		config.Impls = Available()
+	}
+	if len(config.MWMs) == 0 {
+		config.MWMs = defaults.MWMs
+	}
+	if len(config.Threads) == 0 {
+		config.Threads = defaults.Threads
+	}
+	if config.Seconds == 0 {
+		config.Seconds = defaults.Seconds
+	}
+	return config
+}
+
+// Validate checks the config with its defaults filled in. the mwm bounds are passed in
+// as they are defined by the spammer.
+func (config BenchmarkConfig) Validate(minMWM int, maxMWM int) error {
+	config = config.WithDefaults()
+	for _, mwm := range config.MWMs {
+		if mwm < minMWM || mwm > maxMWM {
+			return fmt.Errorf("mwm must be between %d and %d", minMWM, maxMWM)
+		}
+	}
+	for _, threads := range config.Threads {
+		if threads < 1 {
+			return fmt.Errorf("threads must be at least 1")
+		}
+	}
+	if config.Seconds < 1 || config.Seconds > MaxBenchmarkSeconds {
+		return fmt.Errorf("seconds must be between 1 and %d", MaxBenchmarkSeconds)
+	}
+	if runs := len(config.Impls) * len(config.MWMs) * len(config.Threads); runs > MaxBenchmarkRuns {
+		return fmt.Errorf("%d combinations of impls, mwms and threads exceed the max of %d", runs, MaxBenchmarkRuns)
+	}
+	return nil
+}
+
+// BenchmarkRun is the measurement of one implementation with a given mwm and amount of threads.
+type BenchmarkRun struct {
+	Impl         string  `json:"impl"`
+	MWM          int     `json:"mwm"`
+	Threads      int     `json:"threads"`
+	PoWs         int     `json:"pows"`
+	AvgMillis    float64 `json:"avg_millis"`
+	PoWPerSecond float64 `json:"pow_per_second"`
+	Error        string  `json:"error,omitempty"`
+}
+
+type BenchmarkResult struct {
+	NumCPU   int            `json:"num_cpu"`
+	Started  time.Time      `json:"started"`
+	Finished time.Time      `json:"finished"`
+	Runs     []BenchmarkRun `json:"runs"`
+	// a spammer started before all runs were measured
+	Canceled bool `json:"canceled,omitempty"`
+}
+
+// BenchmarkStatus tells whether a benchmark is running and holds the result of the last finished one.
+type BenchmarkStatus struct {
+	Running bool `json:"running"`
+	// config of the running benchmark
+	Config *BenchmarkConfig `json:"config,omitempty"`
+	// nil if no benchmark finished yet
+	Last *BenchmarkResult `json:"last"`
+}
+
+var ErrBenchmarkRunning = errors.New("a benchmark is already running")
+
+// the benchmark changes the thread count of giota, which is shared with the spammer
+var ErrSpammerRunning = errors.New("a benchmark can't run while a spammer is running")
+
+var (
+	muStatus sync.Mutex
+	status   BenchmarkStatus
+)
+
+// LastBenchmark returns the result of the last finished benchmark or nil if none was run yet.
+func LastBenchmark() *BenchmarkResult {
+	muStatus.Lock()
+	defer muStatus.Unlock()
+	return status.Last
+}
+
+// Status returns the status of the benchmarks.
+func Status() BenchmarkStatus {
+	muStatus.Lock()
+	defer muStatus.Unlock()
+	return status
+}
+
+// Benchmark measures the PoW throughput of the configured implementations, the config
+// should be validated beforehand. the result is cached and available through LastBenchmark() afterwards.
+// only one benchmark runs at a time and none while a spammer is running, a spammer started
+// meanwhile cancels the remaining runs.
+func Benchmark(config BenchmarkConfig) (*BenchmarkResult, error) {
+	config = config.WithDefaults()
+	// take over the PoW thread count, the configured one is restored afterwards
+	if err := beginBenchmark(); err != nil {
+		return nil, err
+	}
+	setRunning(config)
+	return benchmark(config), nil
+}
+
+// StartBenchmark runs the benchmark like Benchmark() in the background,
+// its progress is available through Status().
+func StartBenchmark(config BenchmarkConfig) error {
+	config = config.WithDefaults()
+	if err := beginBenchmark(); err != nil {
+		return err
+	}
+	setRunning(config)
+	go benchmark(config)
+	return nil
+}
+
+func setRunning(config BenchmarkConfig) {
+	muStatus.Lock()
+	status.Running = true
+	status.Config = &config
+	muStatus.Unlock()
+}
+
+func benchmark(config BenchmarkConfig) *BenchmarkResult {
+	defer endBenchmark()
+
+	result := &BenchmarkResult{NumCPU: runtime.NumCPU(), Started: time.Now()}
+runs:
+	for _, impl := range config.Impls {
+		for _, mwm := range config.MWMs {
+			for _, threads := range config.Threads {
+				if !setBenchmarkThreads(threads) {
+					break runs
+				}
+				run := benchmarkRun(impl, mwm, time.Duration(config.Seconds)*time.Second)
+				run.Threads = threads
+				result.Runs = append(result.Runs, run)
+			}
+		}
+	}
+	result.Finished = time.Now()
+	result.Canceled = benchmarkCanceled()
+
+	muStatus.Lock()
+	status = BenchmarkStatus{Last: result}
+	muStatus.Unlock()
+	return result
+}
+
+func benchmarkRun(impl string, mwm int, duration time.Duration) BenchmarkRun {
+	run := BenchmarkRun{Impl: impl, MWM: mwm}
+	powFunc, err := giota.GetPowFunc(impl)
+	if err != nil {
+		run.Error = err.Error()
+		return run
+	}
+
+	start := time.Now()
+	// a canceled run stops after its current PoW, which still uses the thread count of the benchmark
+	for time.Since(start) < duration && !benchmarkCanceled() {
+		if _, err := powFunc(randomTxTrytes(), mwm); err != nil {
+			run.Error = err.Error()
+			return run
+		}
+		run.PoWs++
+	}
+
+	if run.PoWs == 0 {
+		run.Error = "canceled before the first PoW finished"
+		return run
+	}
+	elapsed := time.Since(start)
+	run.AvgMillis = elapsed.Seconds() * 1000 / float64(run.PoWs)
+	run.PoWPerSecond = float64(run.PoWs) / elapsed.Seconds()
+	return run
+}
+
+// randomTxTrytes returns random transaction trytes so that each PoW starts from a different state
+func randomTxTrytes() giota.Trytes {
+	trytes := make([]byte, txTrytesSize)
+	for i := range trytes {
+		trytes[i] = tryteAlphabet[rand.Intn(len(tryteAlphabet))]
+	}
+	return giota.Trytes(trytes)
+}
@@ -0,0 +1,39 @@
+package pow
+
+import (
+	"testing"
+
+	"github.com/CWarner818/giota"
+)
+
+func TestBenchmarkNotWhileSpamming(t *testing.T) {
+	SpammerStarted()
+	_, err := Benchmark(BenchmarkConfig{Impls: []string{"none"}, Seconds: 1})
+	SpammerStopped()
+	if err != ErrSpammerRunning {
+		t.Fatalf("expected %v, got %v", ErrSpammerRunning, err)
+	}
+}
+
+func TestSpammerCancelsBenchmark(t *testing.T) {
+	if err := beginBenchmark(); err != nil {
+		t.Fatal(err)
+	}
+	if err := beginBenchmark(); err != ErrBenchmarkRunning {
+		t.Errorf("expected %v, got %v", ErrBenchmarkRunning, err)
+	}
+	if !setBenchmarkThreads(threads + 1) {
+		t.Fatal("expected the benchmark to set the thread count")
+	}
+
+	// the spammer gets the configured thread count back right away
+	SpammerStarted()
+	defer SpammerStopped()
+	if giota.PowProcs != threads {
+		t.Errorf("expected %d threads, got %d", threads, giota.PowProcs)
+	}
+	if setBenchmarkThreads(threads+1) || giota.PowProcs != threads {
+		t.Error("expected the canceled benchmark to leave the thread count alone")
+	}
+	endBenchmark()
+}
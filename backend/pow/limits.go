@@ -0,0 +1,146 @@
+package pow
+
+import (
+	"github.com/CWarner818/giota"
+	"runtime"
+	"sync"
+)
+
+// Limits restrict the host resources used by local PoW. zero values restore the defaults of the process.
+type Limits struct {
+	// max amount of threads a single PoW uses
+	Threads int
+	// GOMAXPROCS of the slave process
+	MaxProcs int
+	// scheduling niceness of the slave process
+	Niceness int
+}
+
+var (
+	muThreads sync.Mutex
+	// the thread count giota uses by default, restored when the limit is lifted
+	defaultThreads = giota.PowProcs
+	threads        = giota.PowProcs
+	benchmarking   bool
+	// the running benchmark stops changing the thread count once a spammer started
+	benchCanceled bool
+	// running spammers, no benchmark is started meanwhile
+	spammers int
+
+	muMaxProcs sync.Mutex
+	// GOMAXPROCS at startup, restored when the limit is lifted
+	defaultMaxProcs = runtime.GOMAXPROCS(0)
+
+	muNiceness sync.Mutex
+	// niceness at startup, restored when the limit is lifted
+	defaultNiceness, _ = getNiceness()
+	niceness           = defaultNiceness
+)
+
+// ApplyLimits applies the given limits to the slave process.
+// an error is returned if the niceness couldn't be changed, the other limits are applied regardless.
+func ApplyLimits(limits Limits) error {
+	setThreads(limits.Threads)
+	setMaxProcs(limits.MaxProcs)
+	return applyNiceness(limits.Niceness)
+}
+
+// applyNiceness sets the niceness on all threads of the process, threads created since the last call
+// included. platforms without support only fail if the niceness differs from the one at startup.
+func applyNiceness(n int) error {
+	muNiceness.Lock()
+	defer muNiceness.Unlock()
+	if n == 0 {
+		n = defaultNiceness
+	}
+	if n == defaultNiceness && niceness == defaultNiceness {
+		return nil
+	}
+	if err := setNiceness(n); err != nil {
+		return err
+	}
+	niceness = n
+	return nil
+}
+
+func setThreads(n int) {
+	muThreads.Lock()
+	defer muThreads.Unlock()
+	if n <= 0 {
+		n = defaultThreads
+	}
+	threads = n
+	// a running benchmark restores the thread count after it finished
+	if !benchmarking {
+		giota.PowProcs = n
+	}
+}
+
+func setMaxProcs(n int) {
+	muMaxProcs.Lock()
+	defer muMaxProcs.Unlock()
+	if n <= 0 {
+		n = defaultMaxProcs
+	}
+	runtime.GOMAXPROCS(n)
+}
+
+// SpammerStarted registers a running spammer until SpammerStopped is called.
+// a running benchmark is canceled and the configured thread count is restored immediately.
+func SpammerStarted() {
+	muThreads.Lock()
+	defer muThreads.Unlock()
+	spammers++
+	if benchmarking && !benchCanceled {
+		benchCanceled = true
+		giota.PowProcs = threads
+	}
+}
+
+// SpammerStopped unregisters a spammer registered with SpammerStarted.
+func SpammerStopped() {
+	muThreads.Lock()
+	defer muThreads.Unlock()
+	spammers--
+}
+
+// beginBenchmark gives the benchmark control over the PoW thread count
+func beginBenchmark() error {
+	muThreads.Lock()
+	defer muThreads.Unlock()
+	if benchmarking {
+		return ErrBenchmarkRunning
+	}
+	if spammers > 0 {
+		return ErrSpammerRunning
+	}
+	benchmarking = true
+	benchCanceled = false
+	return nil
+}
+
+// setBenchmarkThreads sets the thread count of the next benchmark run, false if the benchmark was canceled
+func setBenchmarkThreads(n int) bool {
+	muThreads.Lock()
+	defer muThreads.Unlock()
+	if benchCanceled {
+		return false
+	}
+	giota.PowProcs = n
+	return true
+}
+
+// benchmarkCanceled tells whether a spammer started during the benchmark
+func benchmarkCanceled() bool {
+	muThreads.Lock()
+	defer muThreads.Unlock()
+	return benchCanceled
+}
+
+// endBenchmark restores the configured PoW thread count
+func endBenchmark() {
+	muThreads.Lock()
+	benchmarking = false
+	giota.PowProcs = threads
+	muThreads.Unlock()
+}
@@ -0,0 +1,41 @@
+package pow
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestMaxProcsRestored(t *testing.T) {
+	defer runtime.GOMAXPROCS(defaultMaxProcs)
+
+	if err := ApplyLimits(Limits{MaxProcs: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if procs := runtime.GOMAXPROCS(0); procs != 1 {
+		t.Fatalf("expected GOMAXPROCS 1, got %d", procs)
+	}
+
+	if err := ApplyLimits(Limits{}); err != nil {
+		t.Fatal(err)
+	}
+	if procs := runtime.GOMAXPROCS(0); procs != defaultMaxProcs {
+		t.Errorf("expected GOMAXPROCS to be restored to %d, got %d", defaultMaxProcs, procs)
+	}
+}
+
+func TestNicenessRestored(t *testing.T) {
+	if err := ApplyLimits(Limits{Niceness: defaultNiceness + 1}); err != nil {
+		t.Skipf("unable to change the niceness: %v", err)
+	}
+	if n, _ := getNiceness(); n != defaultNiceness+1 {
+		t.Fatalf("expected niceness %d, got %d", defaultNiceness+1, n)
+	}
+
+	// lowering the niceness again needs privileges
+	if err := ApplyLimits(Limits{}); err != nil {
+		t.Skipf("unable to restore the niceness: %v", err)
+	}
+	if n, _ := getNiceness(); n != defaultNiceness {
+		t.Errorf("expected the niceness to be restored to %d, got %d", defaultNiceness, n)
+	}
+}
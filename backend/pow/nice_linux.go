@@ -0,0 +1,35 @@
+package pow
+
+import (
+	"io/ioutil"
+	"strconv"
+	"syscall"
+)
+
+// setNiceness changes the niceness of every thread of the process, as on linux
+// the priority of PRIO_PROCESS only applies to the calling thread.
+func setNiceness(niceness int) error {
+	tasks, err := ioutil.ReadDir("/proc/self/task")
+	if err != nil {
+		return syscall.Setpriority(syscall.PRIO_PROCESS, 0, niceness)
+	}
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, niceness); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getNiceness returns the niceness of the process, the raw priority of linux is 20 - niceness
+func getNiceness() (int, error) {
+	prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, 0)
+	if err != nil {
+		return 0, err
+	}
+	return 20 - prio, nil
+}
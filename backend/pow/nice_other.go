@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package pow
+
+import "github.com/pkg/errors"
+
+var ErrNicenessUnsupported = errors.New("changing the niceness is not supported on this platform")
+
+func setNiceness(niceness int) error {
+	return ErrNicenessUnsupported
+}
+
+func getNiceness() (int, error) {
+	return 0, nil
+}
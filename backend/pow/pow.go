@@ -0,0 +1,33 @@
+package pow
+
+import (
+	"github.com/CWarner818/giota"
+	"sort"
+)
+
+// Available returns the names of the PoW implementations supported on this host.
+func Available() []string {
+	names := giota.GetPowFuncNames()
+	sort.Strings(names)
+	return names
+}
+
+// Best returns the name of the fastest PoW implementation supported on this host.
+func Best() string {
+	name, _ := giota.GetBestPoW()
+	return name
+}
+
+// Select returns the PoW implementation with the given name.
+// if the name is empty or the implementation isn't supported on this host, the best
+// available implementation is returned instead and fellBack is set if a name was given.
+func Select(name string) (selected string, pow giota.PowFunc, fellBack bool) {
+	if name != "" {
+		if pow, err := giota.GetPowFunc(name); err == nil {
+			return name, pow, false
+		}
+		fellBack = true
+	}
+	selected, pow = giota.GetBestPoW()
+	return selected, pow, fellBack
+}
@@ -0,0 +1,234 @@
+package prom
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are histogram buckets in seconds suited for node requests and PoW.
+var DefaultBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Default is the registry exposed by the slave.
+var Default = NewRegistry()
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// Registry holds metric families and writes them in the Prometheus text format.
+type Registry struct {
+	mu   sync.Mutex
+	vecs map[string]*Vec
+}
+
+func NewRegistry() *Registry {
+	return &Registry{vecs: map[string]*Vec{}}
+}
+
+// Vec is a family of counters, gauges or histograms sharing a name and label names.
+type Vec struct {
+	name    string
+	help    string
+	typ     string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	fn          func() float64
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+// Counter returns the counter family with the given name, registering it if needed.
+func (r *Registry) Counter(name string, help string, labels ...string) *Vec {
+	return r.register(name, help, typeCounter, nil, labels)
+}
+
+// Gauge returns the gauge family with the given name, registering it if needed.
+func (r *Registry) Gauge(name string, help string, labels ...string) *Vec {
+	return r.register(name, help, typeGauge, nil, labels)
+}
+
+// Histogram returns the histogram family with the given name, registering it if needed.
+func (r *Registry) Histogram(name string, help string, buckets []float64, labels ...string) *Vec {
+	return r.register(name, help, typeHistogram, buckets, labels)
+}
+
+func (r *Registry) register(name string, help string, typ string, buckets []float64, labels []string) *Vec {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if vec, ok := r.vecs[name]; ok {
+		return vec
+	}
+	vec := &Vec{name: name, help: help, typ: typ, labels: labels, buckets: buckets, series: map[string]*series{}}
+	r.vecs[name] = vec
+	return vec
+}
+
+// get returns the series of the given label values, must be called with mu held
+func (v *Vec) get(labelValues []string) *series {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", v.name, len(v.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labelValues: labelValues}
+		if v.typ == typeHistogram {
+			s.counts = make([]uint64, len(v.buckets))
+		}
+		v.series[key] = s
+	}
+	return s
+}
+
+// Inc increments the counter or gauge with the given label values by one.
+func (v *Vec) Inc(labelValues ...string) {
+	v.Add(1, labelValues...)
+}
+
+// Add adds the given delta to the counter or gauge with the given label values.
+func (v *Vec) Add(delta float64, labelValues ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.get(labelValues).value += delta
+}
+
+// Set sets the gauge with the given label values.
+func (v *Vec) Set(value float64, labelValues ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.get(labelValues).value = value
+}
+
+// SetFunc makes the gauge with the given label values report the result of fn on each scrape.
+func (v *Vec) SetFunc(fn func() float64, labelValues ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.get(labelValues).fn = fn
+}
+
+// Observe adds an observation to the histogram with the given label values.
+func (v *Vec) Observe(value float64, labelValues ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s := v.get(labelValues)
+	for i, upper := range v.buckets {
+		if value <= upper {
+			s.counts[i]++
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+// Write writes all metric families in the Prometheus text format.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.vecs))
+	for name := range r.vecs {
+		names = append(names, name)
+	}
+	r.mu.Unlock()
+	sort.Strings(names)
+
+	buf := bufio.NewWriter(w)
+	for _, name := range names {
+		r.mu.Lock()
+		vec := r.vecs[name]
+		r.mu.Unlock()
+		vec.write(buf)
+	}
+	return buf.Flush()
+}
+
+func (v *Vec) write(w *bufio.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(v.series) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, escapeHelp(v.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.name, v.typ)
+
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := v.series[key]
+		if v.typ != typeHistogram {
+			value := s.value
+			if s.fn != nil {
+				value = s.fn()
+			}
+			fmt.Fprintf(w, "%s%s %s\n", v.name, v.labelPairs(s.labelValues, ""), formatFloat(value))
+			continue
+		}
+		for i, upper := range v.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, v.labelPairs(s.labelValues, formatFloat(upper)), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, v.labelPairs(s.labelValues, "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.name, v.labelPairs(s.labelValues, ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.name, v.labelPairs(s.labelValues, ""), s.count)
+	}
+}
+
+// labelPairs formats the labels of a series, le is added for histogram buckets if not empty
+func (v *Vec) labelPairs(labelValues []string, le string) string {
+	pairs := make([]string, 0, len(labelValues)+1)
+	for i, label := range v.labels {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", label, escapeLabel(labelValues[i])))
+	}
+	if le != "" {
+		pairs = append(pairs, fmt.Sprintf("le=\"%s\"", le))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer("\\", `\\`, "\n", `\n`, "\"", `\"`)
+var helpEscaper = strings.NewReplacer("\\", `\\`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
@@ -0,0 +1,51 @@
+package prom
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	r := NewRegistry()
+	requests := r.Counter("test_requests_total", "Requests sent\nto the node.", "command")
+	requests.Inc("getNodeInfo")
+	requests.Add(2, "attachToTangle")
+	// a series added with zero is written before it is incremented
+	requests.Add(0, "broadcastTransactions")
+	r.Gauge("test_running", "Whether the spammer runs.").SetFunc(func() float64 { return 1 })
+	r.Gauge("test_unused", "Never set, not written.")
+	r.Histogram("test_duration_seconds", "Request durations.", []float64{0.1, 1}, "name").Observe(0.5, `a "b"`)
+
+	buf := &bytes.Buffer{}
+	if err := r.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP test_duration_seconds Request durations.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{name="a \"b\"",le="0.1"} 0
+test_duration_seconds_bucket{name="a \"b\"",le="1"} 1
+test_duration_seconds_bucket{name="a \"b\"",le="+Inf"} 1
+test_duration_seconds_sum{name="a \"b\""} 0.5
+test_duration_seconds_count{name="a \"b\""} 1
+# HELP test_requests_total Requests sent\nto the node.
+# TYPE test_requests_total counter
+test_requests_total{command="attachToTangle"} 2
+test_requests_total{command="broadcastTransactions"} 0
+test_requests_total{command="getNodeInfo"} 1
+# HELP test_running Whether the spammer runs.
+# TYPE test_running gauge
+test_running 1
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestLabelValueCount(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a missing label value")
+		}
+	}()
+	NewRegistry().Counter("test_total", "Test.", "a", "b").Inc("only a")
+}
@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// the longest a waiter sleeps before re-evaluating the rate, so that rate changes apply quickly
+const maxWaitSlice = time.Duration(250) * time.Millisecond
+
+// Limiter is a token bucket limiting events to a target rate per second with a given burst size.
+// a rate of zero or less means unlimited.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  int
+	tokens float64
+	last   time.Time
+}
+
+// New creates a new limiter with the given rate and burst.
+func New(rate float64, burst int) *Limiter {
+	l := &Limiter{}
+	l.SetRate(rate, burst)
+	return l
+}
+
+// SetRate changes the rate and burst of the limiter. a burst smaller than one is treated as one.
+// a new limiter starts with a full bucket, afterwards the tokens accrued so far are kept up to the new burst.
+func (l *Limiter) SetRate(rate float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if burst < 1 {
+		burst = 1
+	}
+
+	now := time.Now()
+	if l.last.IsZero() {
+		l.tokens = float64(burst)
+	} else if l.rate > 0 {
+		// tokens accrue at the previous rate until now
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+	}
+	if l.tokens > float64(burst) {
+		l.tokens = float64(burst)
+	}
+	l.rate = rate
+	l.burst = burst
+	l.last = now
+}
+
+// Rate returns the current rate and burst of the limiter.
+func (l *Limiter) Rate() (float64, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate, l.burst
+}
+
+// Wait blocks until an event is allowed to happen or the cancel channel is closed.
+// returns false if the wait was canceled.
+func (l *Limiter) Wait(cancel <-chan struct{}) bool {
+	for {
+		wait := l.reserve()
+		if wait == 0 {
+			return true
+		}
+		if wait > maxWaitSlice {
+			wait = maxWaitSlice
+		}
+		select {
+		case <-time.After(wait):
+		case <-cancel:
+			return false
+		}
+	}
+}
+
+// reserve takes a token if one is available or returns the duration until the next one is
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
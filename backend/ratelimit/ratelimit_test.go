@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnlimited(t *testing.T) {
+	l := New(0, 0)
+	for i := 0; i < 1000; i++ {
+		if wait := l.reserve(); wait != 0 {
+			t.Fatalf("expected no wait without a rate, got %v", wait)
+		}
+	}
+}
+
+func TestBurst(t *testing.T) {
+	l := New(1, 3)
+	for i := 0; i < 3; i++ {
+		if wait := l.reserve(); wait != 0 {
+			t.Fatalf("expected event %d of the burst not to wait, got %v", i, wait)
+		}
+	}
+	if wait := l.reserve(); wait <= 0 || wait > time.Second {
+		t.Errorf("expected a wait of up to a second after the burst, got %v", wait)
+	}
+}
+
+func TestRate(t *testing.T) {
+	l := New(100, 1)
+	start := time.Now()
+	for i := 0; i < 21; i++ {
+		if !l.Wait(nil) {
+			t.Fatal("expected the wait not to be canceled")
+		}
+	}
+	// the first event is covered by the burst
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond || elapsed > time.Second {
+		t.Errorf("expected 20 events at 100/s to take about 200ms, took %v", elapsed)
+	}
+}
+
+func TestWaitCanceled(t *testing.T) {
+	l := New(0.1, 1)
+	l.reserve()
+	cancel := make(chan struct{})
+	close(cancel)
+	if l.Wait(cancel) {
+		t.Error("expected the wait to be canceled")
+	}
+}
+
+func TestSetRate(t *testing.T) {
+	l := New(0.1, 1)
+	l.reserve()
+	l.SetRate(5, 2)
+	if rate, burst := l.Rate(); rate != 5 || burst != 2 {
+		t.Errorf("expected rate 5 and burst 2, got %v and %d", rate, burst)
+	}
+	// the used token isn't given back by the rate change
+	if wait := l.reserve(); wait <= 0 || wait > 200*time.Millisecond {
+		t.Errorf("expected a wait of up to 200ms at the new rate, got %v", wait)
+	}
+}
+
+func TestSetRateMidStream(t *testing.T) {
+	l := New(1000, 10)
+	for i := 0; i < 10; i++ {
+		if wait := l.reserve(); wait != 0 {
+			t.Fatalf("expected event %d of the burst not to wait, got %v", i, wait)
+		}
+	}
+
+	// raising the burst of a drained bucket doesn't allow another burst
+	l.SetRate(1, 100)
+	allowed := 0
+	for i := 0; i < 100; i++ {
+		if l.reserve() == 0 {
+			allowed++
+		}
+	}
+	if allowed > 1 {
+		t.Errorf("expected at most 1 event after the rate change, %d were allowed", allowed)
+	}
+
+	// lowering the burst clamps the accrued tokens
+	l = New(1, 10)
+	l.SetRate(1, 2)
+	allowed = 0
+	for i := 0; i < 10; i++ {
+		if l.reserve() == 0 {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("expected 2 events with the lowered burst, %d were allowed", allowed)
+	}
+}
@@ -0,0 +1,48 @@
+package routers
+
+import (
+	"github.com/iota-tangle-io/spamalot-slave/backend/utilities"
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+	"net/http"
+)
+
+type logLevelsMsg struct {
+	Default    string            `json:"default"`
+	Components map[string]string `json:"components"`
+}
+
+// an empty component sets the default level
+type logLevelMsg struct {
+	Component string `json:"component"`
+	Level     string `json:"level"`
+}
+
+type AdminRouter struct {
+	WebEngine *echo.Echo `inject:""`
+}
+
+func (router *AdminRouter) Init() {
+
+	group := router.WebEngine.Group("/api/admin", noCacheHeaders)
+
+	group.GET("/log-levels", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, newLogLevelsMsg())
+	})
+
+	group.PUT("/log-levels", func(c echo.Context) error {
+		msg := &logLevelMsg{}
+		if err := c.Bind(msg); err != nil {
+			return errors.Wrap(ErrBadRequest, err.Error())
+		}
+		if err := utilities.SetLogLevel(msg.Component, msg.Level); err != nil {
+			return errors.Wrap(ErrBadRequest, err.Error())
+		}
+		return c.JSON(http.StatusOK, newLogLevelsMsg())
+	})
+}
+
+func newLogLevelsMsg() logLevelsMsg {
+	def, components := utilities.LogLevels()
+	return logLevelsMsg{Default: def, Components: components}
+}
@@ -0,0 +1,49 @@
+package routers
+
+import (
+	"github.com/iota-tangle-io/spamalot-slave/backend/controllers"
+	"github.com/iota-tangle-io/spamalot-slave/backend/models"
+	"github.com/iota-tangle-io/spamalot-slave/backend/spam"
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+	"net/http"
+)
+
+type validationErrorsMsg struct {
+	Errors spam.ValidationErrors `json:"errors"`
+}
+
+// templates aren't rendered per transaction, see spam.Template
+const templateWarning = `299 - "templates are re-rendered after each issued transaction, consecutive transactions may share a tag and message"`
+
+type ConfigRouter struct {
+	WebEngine *echo.Echo               `inject:""`
+	Ctrl      *controllers.SpammerCtrl `inject:""`
+}
+
+func (router *ConfigRouter) Init() {
+
+	group := router.WebEngine.Group("/api/config", noCacheHeaders)
+
+	group.GET("", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, router.Ctrl.Config())
+	})
+
+	// applies the given config, a config with a templated tag or message is answered with a warning header
+	group.PUT("", func(c echo.Context) error {
+		config := &models.SpammerConfig{}
+		if err := c.Bind(config); err != nil {
+			return errors.Wrap(ErrBadRequest, err.Error())
+		}
+		if err := router.Ctrl.UpdateConfig(config); err != nil {
+			if errs, ok := err.(spam.ValidationErrors); ok {
+				return c.JSON(http.StatusBadRequest, validationErrorsMsg{Errors: errs})
+			}
+			return err
+		}
+		if spam.IsTemplate(config.Tag) || spam.IsTemplate(config.Message) {
+			c.Response().Header().Set("Warning", templateWarning)
+		}
+		return c.JSON(http.StatusOK, router.Ctrl.Config())
+	})
+}
@@ -0,0 +1,102 @@
+package routers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/iota-tangle-io/spamalot-slave/backend/controllers"
+	"github.com/iota-tangle-io/spamalot-slave/backend/spam"
+	"github.com/labstack/echo"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// interval of the comments keeping idle event streams open through proxies
+const sseKeepAliveInterval = time.Duration(15) * time.Second
+
+// streamEvents streams the METRIC and STATE messages of the websocket as server-sent events.
+// summaries and transactions carry an id, a client reconnecting with Last-Event-ID
+// gets the recent ones it missed replayed.
+func (router *SpammerRouter) streamEvents(c echo.Context) error {
+	var since uint64
+	lastEventID := c.Request().Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		// EventSource can't set headers on the first connect
+		lastEventID = c.QueryParam("last_event_id")
+	}
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "invalid Last-Event-ID")
+		}
+		since = id
+	}
+
+	listener, backlog := router.Ctrl.AddMetricListener(since)
+	defer router.Ctrl.RemoveMetricListener(listener)
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Connection", "keep-alive")
+	res.WriteHeader(http.StatusOK)
+
+	// only replay what a resuming client missed
+	if since != 0 {
+		for _, entry := range backlog {
+			if err := writeEvent(res, entry.ID, wsmsg{MsgType: METRIC, Data: entry.Metric, TS: entry.TS}); err != nil {
+				return nil
+			}
+		}
+	}
+	if err := writeEvent(res, 0, wsmsg{MsgType: STATE, Data: router.Ctrl.State(), TS: time.Now()}); err != nil {
+		return nil
+	}
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+	closed := c.Request().Context().Done()
+	for {
+		var err error
+		select {
+		case msg, ok := <-listener.C():
+			if !ok {
+				// removed for being too slow
+				return nil
+			}
+			switch msg := msg.(type) {
+			case controllers.BacklogEntry:
+				err = writeEvent(res, msg.ID, wsmsg{MsgType: METRIC, Data: msg.Metric, TS: msg.TS})
+			case spam.Metric:
+				err = writeEvent(res, 0, wsmsg{MsgType: METRIC, Data: msg, TS: time.Now()})
+			case *controllers.StatusMsg:
+				err = writeEvent(res, 0, wsmsg{MsgType: STATE, Data: msg, TS: time.Now()})
+			}
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(res, ": keep-alive\n\n")
+			res.Flush()
+		case <-closed:
+			return nil
+		}
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+// writeEvent writes the given message as event, the id is omitted if zero
+func writeEvent(res *echo.Response, id uint64, msg wsmsg) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if id != 0 {
+		if _, err := fmt.Fprintf(res, "id: %d\n", id); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(res, "data: %s\n\n", data); err != nil {
+		return err
+	}
+	res.Flush()
+	return nil
+}
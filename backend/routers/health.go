@@ -0,0 +1,29 @@
+package routers
+
+import (
+	"github.com/iota-tangle-io/spamalot-slave/backend/controllers"
+	"github.com/labstack/echo"
+	"net/http"
+)
+
+type HealthRouter struct {
+	WebEngine *echo.Echo              `inject:""`
+	Ctrl      *controllers.HealthCtrl `inject:""`
+}
+
+func (router *HealthRouter) Init() {
+
+	// liveness, the slave is alive as long as it answers
+	router.WebEngine.GET("/healthz", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, router.Ctrl.Liveness())
+	}, noCacheHeaders)
+
+	// readiness, 503 while the slave can't spam, probes the node
+	router.WebEngine.GET("/readyz", func(c echo.Context) error {
+		health := router.Ctrl.Health()
+		if !health.Ready {
+			return c.JSON(http.StatusServiceUnavailable, health)
+		}
+		return c.JSON(http.StatusOK, health)
+	}, noCacheHeaders)
+}
@@ -74,6 +74,7 @@ func (indexRouter *IndexRouter) Init() {
 		case controllers.ErrInvalidObjectId:
 			fallthrough
 		case ErrBadRequest:
+			statusCode = http.StatusBadRequest
 			message = "bad request"
 
 			// 500 internal server error
@@ -0,0 +1,85 @@
+package routers
+
+import (
+	"github.com/iota-tangle-io/spamalot-slave/backend/controllers"
+	"github.com/iota-tangle-io/spamalot-slave/backend/history"
+	"github.com/iota-tangle-io/spamalot-slave/backend/prom"
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultHistoryRange = time.Hour
+	// number of buckets if no step is given
+	defaultHistoryBuckets = 360
+)
+
+type MetricsRouter struct {
+	WebEngine *echo.Echo     `inject:""`
+	History   *history.Store `inject:""`
+}
+
+func (router *MetricsRouter) Init() {
+
+	// Prometheus scrape endpoint
+	router.WebEngine.GET("/metrics", func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderContentType, prom.ContentType)
+		c.Response().WriteHeader(http.StatusOK)
+		return prom.Default.Write(c.Response())
+	}, noCacheHeaders)
+
+	// downsampled summary history, from and to are unix seconds or RFC3339, step is a duration or seconds
+	router.WebEngine.GET("/api/metrics", func(c echo.Context) error {
+		to, err := parseTime(c.QueryParam("to"), time.Now())
+		if err != nil {
+			return errors.Wrap(ErrBadRequest, "invalid to: "+err.Error())
+		}
+		from, err := parseTime(c.QueryParam("from"), to.Add(-defaultHistoryRange))
+		if err != nil {
+			return errors.Wrap(ErrBadRequest, "invalid from: "+err.Error())
+		}
+		if !from.Before(to) {
+			return errors.Wrap(ErrBadRequest, "from must be before to")
+		}
+		step, err := parseDuration(c.QueryParam("step"), to.Sub(from)/defaultHistoryBuckets)
+		if err != nil {
+			return errors.Wrap(ErrBadRequest, "invalid step: "+err.Error())
+		}
+		if step < time.Second {
+			step = time.Second
+		}
+		spammer := c.QueryParam("spammer")
+		if spammer == "" {
+			spammer = controllers.DashboardSpammer
+		}
+
+		series, err := router.History.Query(spammer, from, to, step)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, series)
+	}, noCacheHeaders)
+}
+
+func parseTime(s string, def time.Time) (time.Time, error) {
+	if s == "" {
+		return def, nil
+	}
+	if unix, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func parseDuration(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	if seconds, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,55 @@
+package routers
+
+import (
+	"github.com/iota-tangle-io/spamalot-slave/backend/controllers"
+	"github.com/iota-tangle-io/spamalot-slave/backend/pow"
+	"github.com/iota-tangle-io/spamalot-slave/backend/spam"
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+	"net/http"
+)
+
+type PoWRouter struct {
+	WebEngine *echo.Echo               `inject:""`
+	Ctrl      *controllers.SpammerCtrl `inject:""`
+}
+
+type powMsg struct {
+	Available []string `json:"available"`
+	Best      string   `json:"best"`
+	Active    string   `json:"active"`
+}
+
+func (router *PoWRouter) Init() {
+
+	group := router.WebEngine.Group("/api/pow", noCacheHeaders)
+
+	group.GET("", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, powMsg{
+			Available: pow.Available(), Best: pow.Best(), Active: router.Ctrl.State().PoW,
+		})
+	})
+
+	bench := router.WebEngine.Group("/api/bench", noCacheHeaders)
+
+	// returns whether a benchmark is running and the last benchmark result
+	bench.GET("", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, pow.Status())
+	})
+
+	// starts a new benchmark with the given configuration in the background,
+	// 409 if a benchmark or a spammer is running
+	bench.POST("", func(c echo.Context) error {
+		config := pow.BenchmarkConfig{}
+		if err := c.Bind(&config); err != nil {
+			return errors.Wrap(ErrBadRequest, err.Error())
+		}
+		if err := config.Validate(spam.MinMWM, spam.MaxMWM); err != nil {
+			return errors.Wrap(ErrBadRequest, err.Error())
+		}
+		if err := pow.StartBenchmark(config); err != nil {
+			return c.String(http.StatusConflict, err.Error())
+		}
+		return c.JSON(http.StatusAccepted, pow.Status())
+	})
+}
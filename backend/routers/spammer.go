@@ -3,8 +3,10 @@ package routers
 import (
 	"github.com/labstack/echo"
 	"github.com/iota-tangle-io/spamalot-slave/backend/controllers"
+	"github.com/iota-tangle-io/spamalot-slave/backend/spam"
 	"github.com/gorilla/websocket"
 	"github.com/iota-tangle-io/spamalot-slave/backend/utilities"
+	"sync"
 	"time"
 )
 
@@ -26,14 +28,30 @@ const (
 	STOP   MsgType = 2
 	METRIC MsgType = 3
 	STATE  MsgType = 4
+	RATE   MsgType = 5
+	EVENT  MsgType = 6
+	ERROR  MsgType = 7
+	// sent by a client to select the metrics it receives, data is a spam.Subscription
+	SUBSCRIBE MsgType = 8
+	// marks the end of the recent metrics replayed after connecting, data is a backlogEndMsg
+	BACKLOG_END MsgType = 9
 )
 
+type backlogEndMsg struct {
+	Count int `json:"count"`
+}
+
 type wsmsg struct {
 	MsgType MsgType     `json:"msg_type"`
 	Data    interface{} `json:"data"`
 	TS      time.Time   `json:"ts"`
 }
 
+type rateMsg struct {
+	TPS   float64 `json:"tps"`
+	Burst int     `json:"burst"`
+}
+
 func newWSMsg() *wsmsg {
 	return &wsmsg{TS: time.Now()}
 }
@@ -46,6 +64,9 @@ func (router *SpammerRouter) Init() {
 	}
 	group := router.WebEngine.Group("/api/spammer")
 
+	// server-sent events for clients which can't use websockets
+	group.GET("/events", router.streamEvents, noCacheHeaders)
+
 	group.GET("", func(c echo.Context) error {
 
 		ws, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
@@ -55,13 +76,16 @@ func (router *SpammerRouter) Init() {
 		defer ws.Close()
 
 		writer := make(chan interface{})
-		metrics := make(chan interface{})
 		stop := make(chan struct{})
 		defer close(stop) // auto-free writer, poller
 
+		// all metrics are sent until the client subscribes to specific ones
+		var muFilter sync.Mutex
+		var filter *spam.MetricFilter
+
 		// subscribe to metrics
-		listenerID := router.Ctrl.AddMetricListener(metrics)
-		defer router.Ctrl.RemoveMetricListener(listenerID)
+		listener, backlog := router.Ctrl.AddMetricListener(0)
+		defer router.Ctrl.RemoveMetricListener(listener)
 
 		// sync WS writer
 		go func() {
@@ -80,14 +104,51 @@ func (router *SpammerRouter) Init() {
 
 		// metrics poller
 		go func() {
+			// replay the recent metrics first
+			for _, entry := range backlog {
+				select {
+				case writer <- wsmsg{MsgType: METRIC, Data: entry.Metric, TS: entry.TS}:
+				case <-stop:
+					return
+				}
+			}
+			select {
+			case writer <- wsmsg{MsgType: BACKLOG_END, Data: backlogEndMsg{Count: len(backlog)}, TS: time.Now()}:
+			case <-stop:
+				return
+			}
+
 			for {
 				select {
-				case metric, ok := <-metrics:
+				case metric, ok := <-listener.C():
 					if !ok {
-						// timeout was reached in controller for metric send
+						// the controller removed the listener for being too slow, disconnect the client
+						ws.Close()
 						return
 					}
-					writer <- wsmsg{MsgType: METRIC, Data: metric, TS: time.Now()}
+					// the controller also pushes state changes which didn't originate from a command and events
+					switch msg := metric.(type) {
+					case *controllers.StatusMsg:
+						writer <- wsmsg{MsgType: STATE, Data: msg, TS: time.Now()}
+					case *spam.Event:
+						writer <- wsmsg{MsgType: EVENT, Data: msg, TS: msg.TS}
+					case controllers.BacklogEntry:
+						muFilter.Lock()
+						allowed := filter == nil || filter.Allow(msg.Metric.Kind)
+						muFilter.Unlock()
+						if allowed {
+							writer <- wsmsg{MsgType: METRIC, Data: msg.Metric, TS: msg.TS}
+						}
+					case spam.Metric:
+						muFilter.Lock()
+						allowed := filter == nil || filter.Allow(msg.Kind)
+						muFilter.Unlock()
+						if allowed {
+							writer <- wsmsg{MsgType: METRIC, Data: metric, TS: time.Now()}
+						}
+					default:
+						writer <- wsmsg{MsgType: METRIC, Data: metric, TS: time.Now()}
+					}
 				case <-stop:
 					return
 				}
@@ -103,9 +164,37 @@ func (router *SpammerRouter) Init() {
 
 			switch msg.MsgType {
 			case START:
-				router.Ctrl.Start()
+				if err := router.Ctrl.Start(); err != nil {
+					logger.Error("unable to start spammer", "err", err.Error())
+					writer <- wsmsg{MsgType: ERROR, Data: SimpleMsg{Msg: err.Error()}, TS: time.Now()}
+				}
 			case STOP:
-				router.Ctrl.Stop()
+				if err := router.Ctrl.Stop(); err != nil {
+					writer <- wsmsg{MsgType: ERROR, Data: SimpleMsg{Msg: err.Error()}, TS: time.Now()}
+				}
+			case RATE:
+				rate := &rateMsg{}
+				if err := spam.DecodeData(msg.Data, rate); err != nil {
+					logger.Error("unable to parse rate msg", "err", err.Error())
+					break
+				}
+				if err := router.Ctrl.SetRate(rate.TPS, rate.Burst); err != nil {
+					logger.Error("unable to set rate", "err", err.Error())
+				}
+			case SUBSCRIBE:
+				sub := spam.Subscription{}
+				if err := spam.DecodeData(msg.Data, &sub); err != nil {
+					logger.Error("unable to parse subscribe msg", "err", err.Error())
+					break
+				}
+				newFilter, err := spam.NewMetricFilter(sub)
+				if err != nil {
+					writer <- wsmsg{MsgType: ERROR, Data: SimpleMsg{Msg: err.Error()}, TS: time.Now()}
+					break
+				}
+				muFilter.Lock()
+				filter = newFilter
+				muFilter.Unlock()
 			}
 
 			// auto send state after each received command
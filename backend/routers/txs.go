@@ -0,0 +1,48 @@
+package routers
+
+import (
+	"fmt"
+	"github.com/iota-tangle-io/spamalot-slave/backend/txlog"
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+	"net/http"
+	"time"
+)
+
+type TxsRouter struct {
+	WebEngine *echo.Echo `inject:""`
+	TxLog     *txlog.Log `inject:""`
+}
+
+func (router *TxsRouter) Init() {
+
+	group := router.WebEngine.Group("/api/txs", noCacheHeaders)
+
+	// export of the sent transactions as csv or jsonl, from and to are unix seconds or RFC3339
+	group.GET("/export", func(c echo.Context) error {
+		format := c.QueryParam("format")
+		contentType := "text/csv; charset=utf-8"
+		switch format {
+		case "", txlog.FormatCSV:
+			format = txlog.FormatCSV
+		case txlog.FormatJSONL:
+			contentType = "application/x-ndjson"
+		default:
+			return errors.Wrap(ErrBadRequest, "format must be csv or jsonl")
+		}
+		from, err := parseTime(c.QueryParam("from"), time.Time{})
+		if err != nil {
+			return errors.Wrap(ErrBadRequest, "invalid from: "+err.Error())
+		}
+		to, err := parseTime(c.QueryParam("to"), time.Now())
+		if err != nil {
+			return errors.Wrap(ErrBadRequest, "invalid to: "+err.Error())
+		}
+
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, contentType)
+		res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"txs.%s\"", format))
+		res.WriteHeader(http.StatusOK)
+		return router.TxLog.Export(res, format, from, to)
+	})
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"github.com/iota-tangle-io/spamalot-slave/backend/utilities"
 	"io/ioutil"
 	"reflect"
 	"strings"
@@ -49,6 +50,25 @@ type AppConfig struct {
 	Name     string
 	Dev      bool
 	Verbose  bool
+	History  HistoryConfig
+	TxLog    TxLogConfig
+	Log      utilities.LogConfig
+}
+
+type HistoryConfig struct {
+	// defaults to ./history
+	Dir string
+	// defaults to 7 days
+	RetentionDays int
+}
+
+type TxLogConfig struct {
+	// defaults to ./txs
+	Dir string
+	// size at which the log is rotated, defaults to 100 MB
+	MaxSizeMB int
+	// number of rotated files kept, defaults to 10
+	MaxFiles int
 }
 
 type NetConfig struct {
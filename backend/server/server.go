@@ -2,7 +2,10 @@ package server
 
 import (
 	"github.com/iota-tangle-io/spamalot-slave/backend/controllers"
+	"github.com/iota-tangle-io/spamalot-slave/backend/history"
 	"github.com/iota-tangle-io/spamalot-slave/backend/routers"
+	"github.com/iota-tangle-io/spamalot-slave/backend/spam"
+	"github.com/iota-tangle-io/spamalot-slave/backend/txlog"
 	"github.com/iota-tangle-io/spamalot-slave/backend/utilities"
 	"fmt"
 	_ "github.com/denisenkom/go-mssqldb"
@@ -12,7 +15,6 @@ import (
 	"github.com/globalsign/mgo"
 	"html/template"
 	"io"
-	"os"
 	"time"
 	"github.com/iota-tangle-io/spamalot-slave/api"
 	"context"
@@ -43,22 +45,57 @@ func (server *Server) Start() {
 
 	// init logger
 	utilities.Debug = appConfig.Verbose
+	if err := utilities.ConfigureLogging(appConfig.Log); err != nil {
+		panic(err)
+	}
 	logger, err := utilities.GetLogger("app")
 	if err != nil {
 		panic(err)
 	}
 	logger.Info("booting up app...")
 
+	// name the slave in tag and message templates
+	spam.SlaveName = appConfig.Name
+
+	// open summary history
+	historyConfig := appConfig.History
+	if historyConfig.Dir == "" {
+		historyConfig.Dir = "./history"
+	}
+	if historyConfig.RetentionDays <= 0 {
+		historyConfig.RetentionDays = 7
+	}
+	historyStore, err := history.Open(historyConfig.Dir, time.Duration(historyConfig.RetentionDays)*24*time.Hour)
+	if err != nil {
+		panic(err)
+	}
+
+	// open log of sent transactions
+	txLogConfig := appConfig.TxLog
+	if txLogConfig.Dir == "" {
+		txLogConfig.Dir = "./txs"
+	}
+	if txLogConfig.MaxSizeMB <= 0 {
+		txLogConfig.MaxSizeMB = 100
+	}
+	if txLogConfig.MaxFiles <= 0 {
+		txLogConfig.MaxFiles = 10
+	}
+	txLog, err := txlog.Open(txLogConfig.Dir, int64(txLogConfig.MaxSizeMB)<<20, txLogConfig.MaxFiles)
+	if err != nil {
+		panic(err)
+	}
+
 	// init web server
 	e := echo.New()
 	server.WebEngine = e
 	server.WebEngine.HideBanner = true
 	if httpConfig.LogRequests {
-		requestLogFile, err := os.Create(fmt.Sprintf("./logs/requests.log"))
+		requestLog, err := utilities.OpenLogFile("requests")
 		if err != nil {
 			panic(err)
 		}
-		e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{Output: requestLogFile}))
+		e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{Output: requestLog}))
 		e.Logger.SetLevel(3)
 	}
 
@@ -74,14 +111,23 @@ func (server *Server) Start() {
 	// create controllers
 	appCtrl := &controllers.AppCtrl{}
 	spammerCtrl := &controllers.SpammerCtrl{}
+	healthCtrl := &controllers.HealthCtrl{}
 	controllers := []controllers.Controller{
-		appCtrl, spammerCtrl,
+		appCtrl, spammerCtrl, healthCtrl,
 	}
 
 	// create routers
 	indexRouter := &routers.IndexRouter{}
 	spammRouter := &routers.SpammerRouter{}
-	rters := []routers.Router{indexRouter, spammRouter}
+	configRouter := &routers.ConfigRouter{}
+	powRouter := &routers.PoWRouter{}
+	metricsRouter := &routers.MetricsRouter{}
+	txsRouter := &routers.TxsRouter{}
+	adminRouter := &routers.AdminRouter{}
+	healthRouter := &routers.HealthRouter{}
+	rters := []routers.Router{
+		indexRouter, spammRouter, configRouter, powRouter, metricsRouter, txsRouter, adminRouter, healthRouter,
+	}
 
 	// create slave layer
 	cooConfig := configuration.Net.Coordinator
@@ -94,6 +140,8 @@ func (server *Server) Start() {
 	if err = g.Provide(
 		&inject.Object{Value: e},
 		&inject.Object{Value: slave},
+		&inject.Object{Value: historyStore},
+		&inject.Object{Value: txLog},
 		&inject.Object{Value: appConfig.Dev, Name: "dev"},
 	); err != nil {
 		panic(err)
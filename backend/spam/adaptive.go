@@ -0,0 +1,117 @@
+package spam
+
+import (
+	"github.com/iota-tangle-io/iota-spamalot.go"
+	"github.com/iota-tangle-io/spamalot-slave/backend/models"
+)
+
+// consecutive calm summaries after which an adjustment is reverted by one step
+const calmSummariesToRevert = 3
+
+// tuner raises the depth (and enables tip filters) while the rate of bad tips is too high
+// and steps back to the configured settings once it calmed down.
+type tuner struct {
+	bounds     models.AdaptiveConfig
+	baseDepth  int
+	depth      int
+	filtersOn  bool
+	badTips    int
+	txs        int
+	calmStreak int
+}
+
+func newTuner(config *models.SpammerConfig) *tuner {
+	if config.Adaptive == nil {
+		return nil
+	}
+	t := &tuner{bounds: *config.Adaptive, baseDepth: int(config.Depth)}
+	t.depth = t.clamp(t.baseDepth)
+	return t
+}
+
+func (t *tuner) clamp(depth int) int {
+	if depth < t.bounds.MinDepth {
+		return t.bounds.MinDepth
+	}
+	if depth > t.bounds.MaxDepth {
+		return t.bounds.MaxDepth
+	}
+	return depth
+}
+
+// tune counts bad tips and transactions, on each summary the bad tip rate of the interval
+// is evaluated. returns whether the settings were adjusted and the evaluated rate.
+func (t *tuner) tune(metric spamalot.Metric) (adjusted bool, badTipRate float64) {
+	switch metric.Kind {
+	case spamalot.INC_BAD_TRUNK, spamalot.INC_BAD_BRANCH, spamalot.INC_BAD_TRUNK_AND_BRANCH:
+		t.badTips++
+		return false, 0
+	case spamalot.INC_SUCCESSFUL_TX, spamalot.INC_FAILED_TX:
+		t.txs++
+		return false, 0
+	case spamalot.SUMMARY:
+	default:
+		return false, 0
+	}
+
+	if t.txs == 0 {
+		return false, 0
+	}
+	badTipRate = float64(t.badTips) / float64(t.txs)
+	t.badTips, t.txs = 0, 0
+
+	prevDepth, prevFilters := t.depth, t.filtersOn
+	if badTipRate > t.bounds.MaxBadTipRate {
+		t.calmStreak = 0
+		t.depth = t.clamp(t.depth + 1)
+		if t.bounds.AdjustFilters {
+			t.filtersOn = true
+		}
+	} else if badTipRate < t.bounds.MaxBadTipRate/2 {
+		t.calmStreak++
+		if t.calmStreak >= calmSummariesToRevert {
+			t.calmStreak = 0
+			if t.depth > t.clamp(t.baseDepth) {
+				t.depth--
+			}
+			if t.depth == t.clamp(t.baseDepth) {
+				t.filtersOn = false
+			}
+		}
+	}
+	return t.depth != prevDepth || t.filtersOn != prevFilters, badTipRate
+}
+
+// options returns the spammer settings for the current adjustment
+func (t *tuner) options(config *models.SpammerConfig) []spamalot.Option {
+	filterTrunk, filterBranch := config.FilterTrunk, config.FilterBranch
+	if t.filtersOn {
+		filterTrunk, filterBranch = true, true
+	}
+	return []spamalot.Option{
+		spamalot.WithDepth(int64(t.depth)),
+		spamalot.FilterTrunk(filterTrunk),
+		spamalot.FilterBranch(filterBranch),
+	}
+}
+
+// adjust feeds the metric to the tuner and applies its adjustments to the spammer
+func (s *Spammer) adjust(metric spamalot.Metric) {
+	adjusted, badTipRate := s.tuner.tune(metric)
+	if !adjusted {
+		return
+	}
+
+	options := s.tuner.options(s.Config)
+	data := map[string]interface{}{
+		"depth": s.tuner.depth, "filters": s.tuner.filtersOn, "bad_tip_rate": badTipRate,
+	}
+	// applied asynchronously as the spammer might wait for its metrics to be relayed
+	go func() {
+		if err := s.UpdateSettings(options...); err != nil {
+			s.logger.Warn("unable to adjust depth", "err", err.Error())
+			return
+		}
+		s.emit(EventDepthAdjusted, "adjusted depth to bad tip rate", data)
+	}()
+}
@@ -0,0 +1,139 @@
+package spam
+
+import (
+	"fmt"
+	"github.com/iota-tangle-io/iota-spamalot.go"
+	"time"
+)
+
+// reasons for which a spammer stopped on its own
+const (
+	StopReasonTxCount   = "tx_count"
+	StopReasonDuration  = "duration"
+	StopReasonTime      = "time"
+	StopReasonErrorRate = "error_rate"
+	// the spammer kept exiting and exhausted its restarts
+	StopReasonCrashed = "crashed"
+)
+
+// autoStop tracks the stop conditions of a spammer run
+type autoStop struct {
+	successfulTxs  int
+	errorRateSince time.Time
+	cancel         chan struct{}
+}
+
+// StopReason returns why the spammer stopped on its own, empty if it didn't.
+func (s *Spammer) StopReason() string {
+	s.muStop.Lock()
+	defer s.muStop.Unlock()
+	return s.stopReason
+}
+
+// beginRun resets the stop conditions and starts the timers of the time based ones
+func (s *Spammer) beginRun() {
+	s.muStop.Lock()
+	defer s.muStop.Unlock()
+	s.stopReason = ""
+	s.run = &autoStop{cancel: make(chan struct{})}
+
+	var deadline time.Time
+	reason := ""
+	if s.Config.StopAfterSeconds > 0 {
+		deadline = time.Now().Add(time.Duration(s.Config.StopAfterSeconds) * time.Second)
+		reason = StopReasonDuration
+	}
+	if s.Config.StopAt != nil && (deadline.IsZero() || s.Config.StopAt.Before(deadline)) {
+		deadline = *s.Config.StopAt
+		reason = StopReasonTime
+	}
+	if deadline.IsZero() {
+		return
+	}
+
+	go func(cancel chan struct{}) {
+		select {
+		case <-time.After(time.Until(deadline)):
+			s.autoStop(reason, fmt.Sprintf("reached %s", deadline.Format(time.RFC3339)))
+		case <-cancel:
+		}
+	}(s.run.cancel)
+}
+
+// endRun stops the timers of the time based stop conditions
+func (s *Spammer) endRun() {
+	s.muStop.Lock()
+	defer s.muStop.Unlock()
+	if s.run != nil {
+		close(s.run.cancel)
+		s.run = nil
+	}
+}
+
+// checkStopConditions evaluates the metric based stop conditions
+func (s *Spammer) checkStopConditions(metric spamalot.Metric) {
+	s.muStop.Lock()
+	if s.run == nil {
+		s.muStop.Unlock()
+		return
+	}
+
+	reason, detail := "", ""
+	switch metric.Kind {
+	case spamalot.INC_SUCCESSFUL_TX:
+		s.run.successfulTxs++
+		if s.Config.StopAfterTxs > 0 && s.run.successfulTxs >= s.Config.StopAfterTxs {
+			reason, detail = StopReasonTxCount, fmt.Sprintf("sent %d transactions", s.run.successfulTxs)
+		}
+	case spamalot.SUMMARY:
+		if s.Config.StopErrorRate <= 0 {
+			break
+		}
+		summary := &Summary{}
+		if err := DecodeData(metric.Data, summary); err != nil {
+			break
+		}
+		if summary.ErrorRate <= s.Config.StopErrorRate {
+			s.run.errorRateSince = time.Time{}
+			break
+		}
+		if s.run.errorRateSince.IsZero() {
+			s.run.errorRateSince = time.Now()
+		}
+		sustained := time.Duration(s.Config.StopErrorRateSeconds) * time.Second
+		if time.Since(s.run.errorRateSince) >= sustained {
+			reason = StopReasonErrorRate
+			detail = fmt.Sprintf("error rate %.2f above %.2f since %s", summary.ErrorRate, s.Config.StopErrorRate,
+				s.run.errorRateSince.Format(time.RFC3339))
+		}
+	}
+	s.muStop.Unlock()
+
+	if reason != "" {
+		s.autoStop(reason, detail)
+	}
+}
+
+// autoStop stops the spammer on its own and reports the reason
+func (s *Spammer) autoStop(reason string, detail string) {
+	s.muStop.Lock()
+	if s.run == nil || s.stopReason != "" {
+		s.muStop.Unlock()
+		return
+	}
+	s.stopReason = reason
+	s.muStop.Unlock()
+
+	s.logger.Info("stopping spammer on its own", "reason", reason, "detail", detail)
+
+	// stopped asynchronously as the spammer might wait for its metrics to be relayed
+	go func() {
+		if err := s.Stop(); err != nil {
+			s.logger.Warn("unable to stop spammer", "reason", reason, "err", err.Error())
+			return
+		}
+		if s.OnAutoStop != nil {
+			s.OnAutoStop(reason)
+		}
+	}()
+}
@@ -0,0 +1,220 @@
+package spam
+
+import (
+	"github.com/CWarner818/giota"
+	"github.com/iota-tangle-io/iota-spamalot.go"
+	"github.com/iota-tangle-io/spamalot-slave/backend/prom"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// metric kinds of the confirmation tracker, beyond the kinds of spamalot
+const (
+	INC_CONFIRMED_TX     spamalot.MetricType = 100
+	CONFIRMATION_SUMMARY spamalot.MetricType = 101
+)
+
+const (
+	// how often the inclusion states of the pending transactions are checked
+	confirmCheckInterval = time.Duration(15) * time.Second
+	// transactions not confirmed within this time are given up on
+	confirmTimeout = time.Duration(30) * time.Minute
+	// hashes per getLatestInclusion request
+	confirmBatchSize = 500
+	// transactions sent while this many are pending aren't tracked
+	maxPendingTxs         = 20000
+	confirmRequestTimeout = time.Duration(30) * time.Second
+)
+
+var (
+	confirmationRateGauge = prom.Default.Gauge("spamalot_confirmation_rate",
+		"Share of the tracked transactions which confirmed within the timeout.", "spammer")
+	confirmTimeHistogram = prom.Default.Histogram("spamalot_confirm_time_seconds",
+		"Time from sending a transaction until its confirmation.",
+		[]float64{30, 60, 120, 300, 600, 900, 1200, 1800}, "spammer")
+)
+
+// ConfirmedTXData is the data of a INC_CONFIRMED_TX metric.
+type ConfirmedTXData struct {
+	Hash string `json:"hash"`
+	// seconds from sending until confirmation
+	ConfirmTime float64 `json:"confirm_time"`
+}
+
+// ConfirmationSummary is the data of a CONFIRMATION_SUMMARY metric.
+type ConfirmationSummary struct {
+	Pending   int `json:"pending"`
+	Confirmed int `json:"confirmed"`
+	// transactions which didn't confirm within the timeout
+	Expired int `json:"expired"`
+	// confirmed / (confirmed + expired) since the spammer was created
+	ConfirmationRate float64 `json:"confirmation_rate"`
+	// seconds until confirmation of the transactions confirmed in the last check
+	ConfirmTimeP50 float64 `json:"confirm_time_p50"`
+	ConfirmTimeP90 float64 `json:"confirm_time_p90"`
+	// mean seconds until confirmation since the spammer was created
+	ConfirmTimeAvg float64 `json:"confirm_time_avg"`
+}
+
+// confirmer periodically checks whether the sent transactions got confirmed
+type confirmer struct {
+	api *giota.API
+
+	mu      sync.Mutex
+	pending map[giota.Trytes]time.Time
+
+	confirmed        int
+	expired          int
+	confirmTimeTotal time.Duration
+}
+
+// newConfirmer returns nil if there is no node to ask for inclusion states
+func newConfirmer(node string) *confirmer {
+	if node == "" {
+		return nil
+	}
+	client := &http.Client{Timeout: confirmRequestTimeout}
+	return &confirmer{api: giota.NewAPI(node, client), pending: map[giota.Trytes]time.Time{}}
+}
+
+func (c *confirmer) track(hash string, sent time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.pending) >= maxPendingTxs {
+		return
+	}
+	c.pending[giota.Trytes(hash)] = sent
+}
+
+// check queries the inclusion states of the pending transactions against the latest milestone
+// and returns the newly confirmed ones with their time to confirm
+func (c *confirmer) check(now time.Time) (map[giota.Trytes]time.Duration, error) {
+	c.mu.Lock()
+	hashes := make([]giota.Trytes, 0, len(c.pending))
+	for hash := range c.pending {
+		hashes = append(hashes, hash)
+	}
+	c.mu.Unlock()
+
+	confirmed := map[giota.Trytes]time.Duration{}
+	for start := 0; start < len(hashes); start += confirmBatchSize {
+		end := start + confirmBatchSize
+		if end > len(hashes) {
+			end = len(hashes)
+		}
+		batch := hashes[start:end]
+		states, err := c.api.GetLatestInclusion(batch)
+		if err != nil {
+			return confirmed, err
+		}
+
+		c.mu.Lock()
+		for i, included := range states {
+			if i >= len(batch) || !included {
+				continue
+			}
+			sent, ok := c.pending[batch[i]]
+			if !ok {
+				continue
+			}
+			delete(c.pending, batch[i])
+			confirmed[batch[i]] = now.Sub(sent)
+			c.confirmed++
+			c.confirmTimeTotal += now.Sub(sent)
+		}
+		c.mu.Unlock()
+	}
+	return confirmed, nil
+}
+
+// expire gives up on the transactions pending for longer than the timeout
+func (c *confirmer) expire(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for hash, sent := range c.pending {
+		if now.Sub(sent) > confirmTimeout {
+			delete(c.pending, hash)
+			c.expired++
+		}
+	}
+}
+
+func (c *confirmer) summary(confirmed map[giota.Trytes]time.Duration) *ConfirmationSummary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	summary := &ConfirmationSummary{Pending: len(c.pending), Confirmed: c.confirmed, Expired: c.expired}
+	if resolved := c.confirmed + c.expired; resolved > 0 {
+		summary.ConfirmationRate = float64(c.confirmed) / float64(resolved)
+	}
+	if c.confirmed > 0 {
+		summary.ConfirmTimeAvg = (c.confirmTimeTotal / time.Duration(c.confirmed)).Seconds()
+	}
+	if len(confirmed) > 0 {
+		durations := make([]time.Duration, 0, len(confirmed))
+		for _, d := range confirmed {
+			durations = append(durations, d)
+		}
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		summary.ConfirmTimeP50 = percentile(durations, 0.5).Seconds()
+		summary.ConfirmTimeP90 = percentile(durations, 0.9).Seconds()
+	}
+	return summary
+}
+
+func (c *confirmer) tracking() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending) > 0 || c.confirmed > 0 || c.expired > 0
+}
+
+// trackConfirmations checks the sent transactions each interval and relays the results as metrics
+// until the spammer is closed
+func (s *Spammer) trackConfirmations() {
+	ticker := time.NewTicker(confirmCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.done:
+			return
+		}
+		if !s.confirmer.tracking() {
+			continue
+		}
+
+		now := time.Now()
+		confirmed, err := s.confirmer.check(now)
+		if err != nil {
+			s.logger.Warn("unable to check inclusion states", "err", err.Error())
+		}
+		s.confirmer.expire(now)
+
+		name := s.Name()
+		for hash, d := range confirmed {
+			confirmTimeHistogram.Observe(d.Seconds(), name)
+			if !s.relayMetric(spamalot.Metric{
+				Kind: INC_CONFIRMED_TX, Data: ConfirmedTXData{Hash: string(hash), ConfirmTime: d.Seconds()},
+			}) {
+				return
+			}
+		}
+		summary := s.confirmer.summary(confirmed)
+		confirmationRateGauge.Set(summary.ConfirmationRate, name)
+		if !s.relayMetric(spamalot.Metric{Kind: CONFIRMATION_SUMMARY, Data: summary}) {
+			return
+		}
+	}
+}
+
+// relayMetric passes a metric of the slave itself through the relay of the spammer,
+// returns false if the spammer was closed
+func (s *Spammer) relayMetric(metric spamalot.Metric) bool {
+	select {
+	case s.relay <- metric:
+		return true
+	case <-s.done:
+		return false
+	}
+}
@@ -0,0 +1,32 @@
+package spam
+
+import "time"
+
+// kinds of events emitted by a spammer
+const (
+	EventDepthAdjusted = "depth_adjusted"
+	EventExited        = "spammer_exited"
+	EventRestarting    = "spammer_restarting"
+	EventRestarted     = "spammer_restarted"
+	EventRestartsCap   = "spammer_restarts_exhausted"
+)
+
+// Event is something noteworthy the spammer did on its own.
+type Event struct {
+	Kind string                 `json:"kind"`
+	Msg  string                 `json:"msg"`
+	Data map[string]interface{} `json:"data,omitempty"`
+	TS   time.Time              `json:"ts"`
+}
+
+// emit logs the event and passes it on to OnEvent
+func (s *Spammer) emit(kind string, msg string, data map[string]interface{}) {
+	ctx := []interface{}{"event", kind}
+	for k, v := range data {
+		ctx = append(ctx, k, v)
+	}
+	s.logger.Info(msg, ctx...)
+	if s.OnEvent != nil {
+		s.OnEvent(Event{Kind: kind, Msg: msg, Data: data, TS: time.Now()})
+	}
+}
@@ -0,0 +1,70 @@
+package spam
+
+import (
+	"github.com/iota-tangle-io/iota-spamalot.go"
+	"github.com/iota-tangle-io/spamalot-slave/backend/prom"
+	"time"
+)
+
+var (
+	metricsTotal = prom.Default.Counter("spamalot_metrics_total",
+		"Metrics emitted by the spammer by kind.", "spammer", "kind")
+	tpsGauge = prom.Default.Gauge("spamalot_tps",
+		"Transactions per second of the last summary.", "spammer")
+	targetTPSGauge = prom.Default.Gauge("spamalot_target_tps",
+		"Target transactions per second, 0 means unlimited.", "spammer")
+	errorRateGauge = prom.Default.Gauge("spamalot_error_rate",
+		"Error rate of the last summary.", "spammer")
+	runningGauge = prom.Default.Gauge("spamalot_running",
+		"Whether the spammer is running.", "spammer")
+	nodeRequestDuration = prom.Default.Histogram("spamalot_node_request_duration_seconds",
+		"Duration of the requests the spammer made to its node by command.", prom.DefaultBuckets, "spammer", "command")
+)
+
+// SetName sets the name under which the spammer's metrics are exposed.
+func (s *Spammer) SetName(name string) {
+	s.muName.Lock()
+	s.name = name
+	s.muName.Unlock()
+	// every kind is exposed from the start so that rates over the counters don't miss the first increment
+	for _, kind := range KindNames {
+		metricsTotal.Add(0, name, kind)
+	}
+	runningGauge.SetFunc(func() float64 {
+		if s.IsRunning() {
+			return 1
+		}
+		return 0
+	}, name)
+}
+
+func (s *Spammer) Name() string {
+	s.muName.Lock()
+	defer s.muName.Unlock()
+	return s.name
+}
+
+// instrument records the given metric
+func (s *Spammer) instrument(metric spamalot.Metric) {
+	name := s.Name()
+	metricsTotal.Inc(name, KindNames[metric.Kind])
+	if metric.Kind != spamalot.SUMMARY {
+		return
+	}
+
+	summary := &Summary{}
+	if err := DecodeData(metric.Data, summary); err != nil {
+		return
+	}
+	tpsGauge.Set(summary.TPS, name)
+	errorRateGauge.Set(summary.ErrorRate, name)
+	targetTPS, _ := s.limiter.Rate()
+	targetTPSGauge.Set(targetTPS, name)
+}
+
+func (s *Spammer) observeNodeRequest(command string, duration time.Duration) {
+	nodeRequestDuration.Observe(duration.Seconds(), s.Name(), command)
+	if stage, ok := commandStages[command]; ok {
+		s.observeStage(stage, duration)
+	}
+}
@@ -0,0 +1,34 @@
+package spam
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/iota-tangle-io/spamalot-slave/backend/prom"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+func TestMetricsTotalPreRegistered(t *testing.T) {
+	config := validConfig()
+	config.DryRun = true
+	config.NodeAddress = ""
+	s, err := New(config, make(chan Metric, 16), log15.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.SetName("test-preregistered")
+
+	buf := &bytes.Buffer{}
+	if err := prom.Default.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range KindNames {
+		series := fmt.Sprintf(`spamalot_metrics_total{spammer="test-preregistered",kind="%s"} 0`, kind)
+		if !strings.Contains(buf.String(), series+"\n") {
+			t.Errorf("expected %s", series)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package spam
+
+import (
+	"github.com/CWarner818/giota"
+	"github.com/iota-tangle-io/spamalot-slave/backend/prom"
+	"sort"
+	"sync"
+	"time"
+)
+
+// stages of issuing a transaction
+const (
+	StageTips      = "tips"
+	StagePoW       = "pow"
+	StageBroadcast = "broadcast"
+	StageStore     = "store"
+)
+
+// stages of the node commands, attachToTangle is remote PoW
+var commandStages = map[string]string{
+	"getTransactionsToApprove": StageTips,
+	"attachToTangle":           StagePoW,
+	"broadcastTransactions":    StageBroadcast,
+	"storeTransactions":        StageStore,
+}
+
+var (
+	stageDuration = prom.Default.Histogram("spamalot_stage_duration_seconds",
+		"Duration of each stage of issuing a transaction.", prom.DefaultBuckets, "spammer", "stage")
+	stageQuantile = prom.Default.Gauge("spamalot_stage_duration_quantile_seconds",
+		"Quantiles of the stage durations within the last summary interval.", "spammer", "stage", "quantile")
+)
+
+// Percentiles are the durations of a stage within a summary interval in milliseconds.
+type Percentiles struct {
+	Count int     `json:"count"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+}
+
+// latencies collects the stage durations of a summary interval
+type latencies struct {
+	mu      sync.Mutex
+	samples map[string][]time.Duration
+}
+
+func newLatencies() *latencies {
+	return &latencies{samples: map[string][]time.Duration{}}
+}
+
+func (l *latencies) observe(stage string, duration time.Duration) {
+	l.mu.Lock()
+	l.samples[stage] = append(l.samples[stage], duration)
+	l.mu.Unlock()
+}
+
+// flush returns the percentiles per stage since the last flush, nil if nothing was observed
+func (l *latencies) flush() map[string]Percentiles {
+	l.mu.Lock()
+	samples := l.samples
+	l.samples = map[string][]time.Duration{}
+	l.mu.Unlock()
+
+	if len(samples) == 0 {
+		return nil
+	}
+	percentiles := map[string]Percentiles{}
+	for stage, durations := range samples {
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		percentiles[stage] = Percentiles{
+			Count: len(durations),
+			P50:   millis(percentile(durations, 0.5)),
+			P90:   millis(percentile(durations, 0.9)),
+			P99:   millis(percentile(durations, 0.99)),
+			Max:   millis(durations[len(durations)-1]),
+		}
+	}
+	return percentiles
+}
+
+// percentile returns the nearest-rank percentile of the given sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func (s *Spammer) observeStage(stage string, duration time.Duration) {
+	s.latencies.observe(stage, duration)
+	stageDuration.Observe(duration.Seconds(), s.Name(), stage)
+}
+
+// timePoW wraps the given PoW function to observe the duration of local PoW
+func (s *Spammer) timePoW(powFunc giota.PowFunc) giota.PowFunc {
+	if powFunc == nil {
+		return nil
+	}
+	return func(trytes giota.Trytes, mwm int) (giota.Trytes, error) {
+		start := time.Now()
+		defer func() { s.observeStage(StagePoW, time.Since(start)) }()
+		return powFunc(trytes, mwm)
+	}
+}
+
+// flushLatencies returns the stage percentiles of the ended summary interval and exposes them as quantiles
+func (s *Spammer) flushLatencies() map[string]Percentiles {
+	percentiles := s.latencies.flush()
+	name := s.Name()
+	for stage, p := range percentiles {
+		stageQuantile.Set(p.P50/1000, name, stage, "0.5")
+		stageQuantile.Set(p.P90/1000, name, stage, "0.9")
+		stageQuantile.Set(p.P99/1000, name, stage, "0.99")
+	}
+	return percentiles
+}
@@ -0,0 +1,49 @@
+package spam
+
+import (
+	"encoding/json"
+	"github.com/iota-tangle-io/iota-spamalot.go"
+)
+
+// KindNames are the names of the spamalot metric kinds.
+var KindNames = map[spamalot.MetricType]string{
+	spamalot.INC_MILESTONE_BRANCH:     "INC_MILESTONE_BRANCH",
+	spamalot.INC_MILESTONE_TRUNK:      "INC_MILESTONE_TRUNK",
+	spamalot.INC_BAD_TRUNK:            "INC_BAD_TRUNK",
+	spamalot.INC_BAD_BRANCH:           "INC_BAD_BRANCH",
+	spamalot.INC_BAD_TRUNK_AND_BRANCH: "INC_BAD_TRUNK_AND_BRANCH",
+	spamalot.INC_FAILED_TX:            "INC_FAILED_TX",
+	spamalot.INC_SUCCESSFUL_TX:        "INC_SUCCESSFUL_TX",
+	spamalot.SUMMARY:                  "SUMMARY",
+	INC_CONFIRMED_TX:                  "INC_CONFIRMED_TX",
+	CONFIRMATION_SUMMARY:              "CONFIRMATION_SUMMARY",
+}
+
+// Summary is the data of a SUMMARY metric.
+type Summary struct {
+	TXsSucceeded      int     `json:"txs_succeeded"`
+	TXsFailed         int     `json:"txs_failed"`
+	BadBranch         int     `json:"bad_branch"`
+	BadTrunk          int     `json:"bad_trunk"`
+	BadTrunkAndBranch int     `json:"bad_trunk_and_branch"`
+	MilestoneTrunk    int     `json:"milestone_trunk"`
+	MilestoneBranch   int     `json:"milestone_branch"`
+	TPS               float64 `json:"tps"`
+	ErrorRate         float64 `json:"error_rate"`
+}
+
+// TXData is the data of a INC_SUCCESSFUL_TX metric.
+type TXData struct {
+	Hash  string `json:"hash"`
+	Count int    `json:"count"`
+}
+
+// DecodeData decodes the data of a metric or a received message into the given object.
+// the data is re-encoded as its concrete type isn't known to the receiver.
+func DecodeData(data interface{}, obj interface{}) error {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(dataBytes, obj)
+}
@@ -0,0 +1,94 @@
+package spam
+
+import (
+	"github.com/iota-tangle-io/iota-spamalot.go"
+	"github.com/iota-tangle-io/spamalot-slave/backend/pow"
+	"time"
+)
+
+const (
+	PoWLocal  = "local"
+	PoWRemote = "remote"
+)
+
+// how long the node may take for attachToTangle before falling back to local PoW
+const remotePoWTimeout = time.Duration(30) * time.Second
+
+// how long hybrid PoW stays local before remote PoW is tried again
+const remotePoWRetryInterval = time.Duration(5) * time.Minute
+
+// PoW returns the name of the local PoW implementation in use, empty if PoW is done remotely.
+func (s *Spammer) PoW() string {
+	s.muPoW.Lock()
+	defer s.muPoW.Unlock()
+	return s.powImpl
+}
+
+// PoWSource returns whether PoW is currently done locally or by the node.
+func (s *Spammer) PoWSource() string {
+	s.muPoW.Lock()
+	defer s.muPoW.Unlock()
+	return s.powSource
+}
+
+func (s *Spammer) useLocalPoW() {
+	s.muPoW.Lock()
+	defer s.muPoW.Unlock()
+	s.switchToLocalPoW()
+}
+
+func (s *Spammer) useRemotePoW() {
+	s.muPoW.Lock()
+	defer s.muPoW.Unlock()
+	s.switchToRemotePoW()
+}
+
+// must be called with muPoW held
+func (s *Spammer) switchToLocalPoW() {
+	name, powFunc, fellBack := pow.Select(s.Config.PoWImpl)
+	if fellBack {
+		s.logger.Warn("PoW implementation not supported on this host, falling back", "requested", s.Config.PoWImpl, "using", name)
+	}
+	s.UpdateSettings(spamalot.WithPoW(s.timePoW(powFunc)))
+	s.UpdateSettings(spamalot.WithNode(s.gateway.Address(), false))
+	s.powImpl = name
+	s.powSource = PoWLocal
+}
+
+// must be called with muPoW held
+func (s *Spammer) switchToRemotePoW() {
+	s.UpdateSettings(spamalot.WithNode(s.gateway.Address(), true))
+	s.powImpl = ""
+	s.powSource = PoWRemote
+}
+
+// onRemotePoWFailure switches hybrid PoW to local PoW and schedules a retry of remote PoW
+func (s *Spammer) onRemotePoWFailure(err error) {
+	s.muPoW.Lock()
+	defer s.muPoW.Unlock()
+	if s.powSource == PoWLocal {
+		return
+	}
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	s.logger.Warn("remote PoW failed, switching to local PoW", "node", s.gateway.Upstream(), "err", err.Error())
+	s.switchToLocalPoW()
+	s.remoteRetry = time.AfterFunc(remotePoWRetryInterval, s.retryRemotePoW)
+}
+
+func (s *Spammer) retryRemotePoW() {
+	s.muPoW.Lock()
+	defer s.muPoW.Unlock()
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	s.logger.Info("retrying remote PoW", "node", s.gateway.Upstream())
+	s.switchToRemotePoW()
+}
@@ -0,0 +1,77 @@
+package spam
+
+import (
+	"github.com/CWarner818/giota"
+	"github.com/iota-tangle-io/spamalot-slave/backend/models"
+	"math/rand"
+)
+
+// rotation strategies for destination addresses
+const (
+	// next address for every transaction
+	RotationRoundRobin = "round-robin"
+	// random address for every transaction
+	RotationRandom = "random"
+	// next address after every RotateEvery transactions
+	RotationPerN = "per-n"
+)
+
+const maxDestCount = 1000
+
+// rotator picks the destination address of each transaction
+type rotator struct {
+	addresses []string
+	strategy  string
+	every     int
+	current   int
+	issued    int
+}
+
+// newRotator returns nil if the config only has a single destination address
+func newRotator(config *models.SpammerConfig) (*rotator, error) {
+	addresses := config.DestAddresses
+	if config.DestSeed != "" {
+		count := config.DestCount
+		if count < 1 {
+			count = 1
+		}
+		for i := 0; i < count; i++ {
+			address, err := giota.NewAddress(giota.Trytes(config.DestSeed), i, config.SecurityLvl)
+			if err != nil {
+				return nil, err
+			}
+			addresses = append(addresses, string(address))
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+
+	strategy := config.Rotation
+	if strategy == "" {
+		strategy = RotationRoundRobin
+	}
+	return &rotator{addresses: addresses, strategy: strategy, every: config.RotateEvery}, nil
+}
+
+func (r *rotator) address() string {
+	return r.addresses[r.current]
+}
+
+// advance rotates past the given amount of issued transactions and returns the destination
+// address for the next transaction and whether it changed
+func (r *rotator) advance(issued int) (string, bool) {
+	prev := r.current
+	switch r.strategy {
+	case RotationRandom:
+		r.current = rand.Intn(len(r.addresses))
+	case RotationPerN:
+		if r.every > 0 {
+			r.current = (r.current + (r.issued+issued)/r.every - r.issued/r.every) % len(r.addresses)
+		}
+	default:
+		r.current = (r.current + issued) % len(r.addresses)
+	}
+	r.issued += issued
+	return r.address(), r.current != prev
+}
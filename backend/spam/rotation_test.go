@@ -0,0 +1,33 @@
+package spam
+
+import "testing"
+
+func TestRotatorAdvance(t *testing.T) {
+	addresses := []string{"A", "B", "C"}
+	tests := []struct {
+		strategy string
+		every    int
+		// transactions issued between the updates
+		issued []int
+		// address after each update
+		expected string
+	}{
+		{RotationRoundRobin, 0, []int{1, 1, 1, 1}, "BCAB"},
+		{RotationRoundRobin, 0, []int{2, 4, 1}, "CAB"},
+		{RotationPerN, 2, []int{1, 1, 1, 1, 1}, "ABBCC"},
+		{RotationPerN, 2, []int{3, 3, 1}, "BAA"},
+		{RotationPerN, 3, []int{9}, "A"},
+	}
+
+	for _, test := range tests {
+		r := &rotator{addresses: addresses, strategy: test.strategy, every: test.every}
+		rotated := ""
+		for _, issued := range test.issued {
+			address, _ := r.advance(issued)
+			rotated += address
+		}
+		if rotated != test.expected {
+			t.Errorf("%s every %d after %v: expected %s, got %s", test.strategy, test.every, test.issued, test.expected, rotated)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package spam
+
+import (
+	"github.com/iota-tangle-io/iota-spamalot.go"
+	"github.com/iota-tangle-io/spamalot-coo/api"
+	"github.com/iota-tangle-io/spamalot-slave/backend/gateway"
+	"github.com/iota-tangle-io/spamalot-slave/backend/models"
+	"github.com/iota-tangle-io/spamalot-slave/backend/pow"
+	"github.com/iota-tangle-io/spamalot-slave/backend/ratelimit"
+	"github.com/iota-tangle-io/spamalot-slave/backend/txlog"
+	"gopkg.in/inconshreveable/log15.v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metric is a spamalot metric as relayed by the slave.
+type Metric struct {
+	spamalot.Metric
+	DryRun bool `json:"dry_run,omitempty"`
+	// target rate at the time of a summary
+	TargetTPS float64 `json:"target_tps,omitempty"`
+	// PoW source at the time of a summary
+	PoWSource string `json:"pow_source,omitempty"`
+	// stage durations within the interval of a summary
+	Latency map[string]Percentiles `json:"latency,omitempty"`
+}
+
+// Spammer is a spamalot spammer talking to its node through a local gateway.
+type Spammer struct {
+	// transactions issued so far, accessed atomically and first for 64-bit alignment
+	txCount uint64
+	*spamalot.Spammer
+	Config  *models.SpammerConfig
+	gateway *gateway.Gateway
+	limiter *ratelimit.Limiter
+	logger  log15.Logger
+	relay   chan spamalot.Metric
+	done    chan struct{}
+	closed  sync.Once
+
+	// signals the per transaction updater that transactions were issued, signals are coalesced
+	// while the updater is busy as it catches up with txCount
+	txIssued  chan struct{}
+	templates *templates
+	rotator   *rotator
+	latencies *latencies
+	// nil in a dry-run
+	confirmer *confirmer
+
+	// called after the spammer stopped on its own because of a stop condition
+	OnAutoStop func(reason string)
+	// called for each event the spammer emits
+	OnEvent    func(event Event)
+	tuner      *tuner
+	muStop     sync.Mutex
+	stopReason string
+	run        *autoStop
+
+	muName sync.Mutex
+	// name under which the metrics are exposed
+	name string
+
+	muSupervisor sync.Mutex
+	// whether the spammer should be running, a spammer exiting while desired is restarted
+	desired  bool
+	restarts []time.Time
+	// closed to cancel the restart the supervisor is waiting for, nil if none is pending
+	pendingRestart chan struct{}
+	// serializes starting the spammer between the supervisor and StartWait
+	muStart sync.Mutex
+
+	muPoW sync.Mutex
+	// name of the PoW implementation in use, empty if PoW is done remotely
+	powImpl     string
+	powSource   string
+	remoteRetry *time.Timer
+
+	muTxLog sync.Mutex
+	// sent transactions are appended to the log if set
+	txLog      *txlog.Log
+	configHash string
+}
+
+// New creates a new spammer from the given configuration which relays its metrics to the given channel.
+// the spammer must be closed after usage in order to free its gateway.
+func New(config *models.SpammerConfig, metrics chan<- Metric, logger log15.Logger) (*Spammer, error) {
+	tmpls, err := newTemplates(config)
+	if err != nil {
+		return nil, err
+	}
+	rot, err := newRotator(config)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Spammer{
+		Config:    config,
+		limiter:   ratelimit.New(config.TargetTPS, config.Burst),
+		logger:    logger,
+		relay:     make(chan spamalot.Metric),
+		done:      make(chan struct{}),
+		txIssued:  make(chan struct{}, 1),
+		templates: tmpls,
+		rotator:   rot,
+		latencies: newLatencies(),
+		tuner:     newTuner(config),
+	}
+
+	tag, message := config.Tag, config.Message
+	if tmpls != nil {
+		tag, message = tmpls.render(config, 0)
+	}
+	depth := int(config.Depth)
+	if s.tuner != nil {
+		depth = s.tuner.depth
+	}
+	destAddress := config.DestAddress
+	if rot != nil {
+		destAddress = rot.address()
+	}
+
+	gwOptions := []gateway.Option{
+		gateway.DryRun(config.DryRun), gateway.RateLimit(s.limiter), gateway.Observe(s.observeNodeRequest),
+	}
+	if config.HybridPoW {
+		gwOptions = append(gwOptions, gateway.OnAttachFailure(s.onRemotePoWFailure, remotePoWTimeout))
+	}
+	gw, err := gateway.New(config.NodeAddress, gwOptions...)
+	if err != nil {
+		return nil, err
+	}
+	s.gateway = gw
+
+	spammer, err := spamalot.New(
+		spamalot.WithMWM(int64(config.MWM)),
+		spamalot.WithDepth(int64(depth)),
+		spamalot.ToAddress(destAddress),
+		spamalot.WithTag(tag),
+		spamalot.WithMessage(message),
+		spamalot.WithSecurityLevel(spamalot.SecurityLevel(config.SecurityLvl)),
+		spamalot.FilterTrunk(config.FilterTrunk),
+		spamalot.FilterBranch(config.FilterBranch),
+		spamalot.FilterMilestone(config.FilterMilestone),
+		spamalot.WithMetricsRelay(s.relay),
+	)
+	if err != nil {
+		gw.Close()
+		return nil, err
+	}
+
+	s.Spammer = spammer
+	s.updateConfigHash()
+	if !config.DryRun {
+		s.confirmer = newConfirmer(config.NodeAddress)
+	}
+
+	// configure PoW, a dry-run always does PoW locally as no node is asked to attach
+	if config.PoWMode == api.POW_LOCAL || config.DryRun {
+		s.useLocalPoW()
+	} else {
+		s.useRemotePoW()
+	}
+
+	go s.relayMetrics(metrics)
+	if s.confirmer != nil {
+		go s.trackConfirmations()
+	}
+	if tmpls != nil || rot != nil {
+		go s.updatePerTx()
+	}
+	return s, nil
+}
+
+// updatePerTx renders the tag and message templates and rotates the destination address
+// by the transactions issued since the last update, the latest settings are applied at once
+func (s *Spammer) updatePerTx() {
+	var applied uint64
+	for {
+		select {
+		case <-s.txIssued:
+			count := atomic.LoadUint64(&s.txCount)
+			issued := int(count - applied)
+			applied = count
+			if issued == 0 {
+				continue
+			}
+
+			var options []spamalot.Option
+			if s.templates != nil {
+				tag, message := s.templates.render(s.Config, count)
+				options = append(options, spamalot.WithTag(tag), spamalot.WithMessage(message))
+			}
+			if s.rotator != nil {
+				if address, changed := s.rotator.advance(issued); changed {
+					options = append(options, spamalot.ToAddress(address))
+				}
+			}
+			if len(options) > 0 {
+				s.UpdateSettings(options...)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Spammer) relayMetrics(metrics chan<- Metric) {
+	for {
+		select {
+		case metric := <-s.relay:
+			s.instrument(metric)
+			s.checkStopConditions(metric)
+			if s.tuner != nil {
+				s.adjust(metric)
+			}
+			relayed := Metric{Metric: metric, DryRun: s.Config.DryRun}
+			switch metric.Kind {
+			case spamalot.SUMMARY:
+				relayed.TargetTPS, _ = s.limiter.Rate()
+				relayed.PoWSource = s.PoWSource()
+				relayed.Latency = s.flushLatencies()
+			case spamalot.INC_SUCCESSFUL_TX, spamalot.INC_FAILED_TX:
+				if metric.Kind == spamalot.INC_SUCCESSFUL_TX {
+					s.logTx(metric)
+				}
+				atomic.AddUint64(&s.txCount, 1)
+				// the updater catches up with all transactions once it is done applying settings
+				select {
+				case s.txIssued <- struct{}{}:
+				default:
+				}
+			}
+			select {
+			case metrics <- relayed:
+			case <-s.done:
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Start applies the configured resource limits and starts the spammer.
+// blocks until the spammer is stopped.
+func (s *Spammer) Start() error {
+	s.applyLimits()
+	pow.SpammerStarted()
+	defer pow.SpammerStopped()
+	return s.Spammer.Start()
+}
+
+func (s *Spammer) applyLimits() {
+	limits := pow.Limits{Threads: s.Config.PoWThreads, MaxProcs: s.Config.MaxProcs, Niceness: s.Config.Niceness}
+	if err := pow.ApplyLimits(limits); err != nil {
+		s.logger.Warn("unable to apply niceness", "niceness", limits.Niceness, "err", err.Error())
+	}
+}
+
+// SetRate changes the target rate of the running spammer.
+func (s *Spammer) SetRate(tps float64, burst int) {
+	s.limiter.SetRate(tps, burst)
+	s.Config.TargetTPS = tps
+	s.Config.Burst = burst
+	s.updateConfigHash()
+}
+
+// UpdateRuntime applies the target rate and resource limits of the given config
+// without restarting the spammer. limits are applied immediately if the spammer is running.
+func (s *Spammer) UpdateRuntime(config *models.SpammerConfig) {
+	s.SetRate(config.TargetTPS, config.Burst)
+	s.Config.PoWThreads = config.PoWThreads
+	s.Config.MaxProcs = config.MaxProcs
+	s.Config.Niceness = config.Niceness
+	s.updateConfigHash()
+	if s.IsRunning() {
+		s.applyLimits()
+	}
+}
+
+// Close stops the spammer if it is running and frees its gateway, closing it again is a no-op.
+func (s *Spammer) Close() error {
+	if err := s.Stop(); err != nil {
+		return err
+	}
+	var err error
+	s.closed.Do(func() {
+		close(s.done)
+		s.muPoW.Lock()
+		if s.remoteRetry != nil {
+			s.remoteRetry.Stop()
+		}
+		s.muPoW.Unlock()
+		err = s.gateway.Close()
+	})
+	return err
+}
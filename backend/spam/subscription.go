@@ -0,0 +1,65 @@
+package spam
+
+import (
+	"fmt"
+	"github.com/iota-tangle-io/iota-spamalot.go"
+	"sync"
+)
+
+// Subscription selects the metrics a client receives.
+type Subscription struct {
+	// kinds to receive, all kinds if empty
+	Kinds []spamalot.MetricType `json:"kinds"`
+	// share of the metrics of each kind to receive, between 0 exclusive and 1, 0 means 1
+	SampleRate float64 `json:"sample_rate"`
+}
+
+// MetricFilter decides which metrics are passed on to a subscribed client.
+type MetricFilter struct {
+	mu    sync.Mutex
+	kinds map[spamalot.MetricType]bool
+	rate  float64
+	// per kind credit, a metric is passed on once a full credit is accumulated
+	credit map[spamalot.MetricType]float64
+}
+
+// NewMetricFilter creates a filter for the given subscription.
+func NewMetricFilter(sub Subscription) (*MetricFilter, error) {
+	if sub.SampleRate < 0 || sub.SampleRate > 1 {
+		return nil, fmt.Errorf("sample rate must be between 0 and 1")
+	}
+	filter := &MetricFilter{rate: sub.SampleRate, credit: map[spamalot.MetricType]float64{}}
+	if filter.rate == 0 {
+		filter.rate = 1
+	}
+	if len(sub.Kinds) > 0 {
+		filter.kinds = map[spamalot.MetricType]bool{}
+		for _, kind := range sub.Kinds {
+			if _, ok := KindNames[kind]; !ok {
+				return nil, fmt.Errorf("unknown metric kind %d", kind)
+			}
+			filter.kinds[kind] = true
+		}
+	}
+	return filter, nil
+}
+
+// Allow reports whether a metric of the given kind is passed on, the first metric of each subscribed kind always is.
+func (filter *MetricFilter) Allow(kind spamalot.MetricType) bool {
+	filter.mu.Lock()
+	defer filter.mu.Unlock()
+	if filter.kinds != nil && !filter.kinds[kind] {
+		return false
+	}
+	credit, ok := filter.credit[kind]
+	if !ok {
+		credit = 1
+	}
+	// tolerate the rounding of accumulated fractional rates
+	allowed := credit >= 1-1e-9
+	if allowed {
+		credit--
+	}
+	filter.credit[kind] = credit + filter.rate
+	return allowed
+}
@@ -0,0 +1,258 @@
+package spam
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// DefaultStartTimeout is how long a spammer may take to get its first answer from the node.
+const DefaultStartTimeout = time.Duration(30) * time.Second
+
+const (
+	// a spammer exiting on its own is restarted at most maxRestarts times within restartWindow
+	maxRestarts   = 5
+	restartWindow = time.Duration(10) * time.Minute
+	minBackoff    = time.Duration(1) * time.Second
+	maxBackoff    = time.Duration(1) * time.Minute
+)
+
+var ErrStartTimeout = errors.New("spammer didn't get an answer from its node in time")
+var ErrStoppedWhileStarting = errors.New("spammer stopped while starting")
+
+// StartWait starts the spammer in the background and blocks until it got its first
+// successful answer from the node, failed to start or the timeout passed.
+// the spammer is stopped again if it didn't start in time. once started, the spammer
+// is supervised and restarted with backoff if it exits or panics without being stopped.
+// a restart the supervisor is waiting for is replaced by this start.
+func (s *Spammer) StartWait(timeout time.Duration) error {
+	s.muSupervisor.Lock()
+	newRun := !s.desired
+	s.desired = true
+	s.cancelRestart()
+	s.muSupervisor.Unlock()
+
+	s.muStart.Lock()
+	defer s.muStart.Unlock()
+	// the supervisor might have restarted the spammer in the meantime
+	if s.IsRunning() {
+		return nil
+	}
+	// the stop conditions apply to the run as a whole, not to each restart
+	if newRun {
+		s.beginRun()
+	}
+
+	if err := s.startWait(timeout); err != nil {
+		s.muSupervisor.Lock()
+		s.desired = false
+		s.muSupervisor.Unlock()
+		s.endRun()
+		return err
+	}
+	// stopped or closed while it was starting
+	if !s.isDesired() {
+		if err := s.Spammer.Stop(); err != nil {
+			s.logger.Warn("unable to stop spammer", "err", err.Error())
+		}
+		return ErrStoppedWhileStarting
+	}
+	return nil
+}
+
+func (s *Spammer) startWait(timeout time.Duration) error {
+	ready := s.gateway.Ready()
+	exited := make(chan error, 1)
+	go func() {
+		// only panics of the goroutine running the spammer can be recovered here
+		defer func() {
+			if r := recover(); r != nil {
+				exited <- fmt.Errorf("spammer panicked: %v", r)
+			}
+		}()
+		exited <- s.Start()
+	}()
+
+	select {
+	case <-ready:
+		go s.supervise(exited)
+		return nil
+	case err := <-exited:
+		if err == nil {
+			err = ErrStoppedWhileStarting
+		}
+		return err
+	case <-time.After(timeout):
+		if err := s.Spammer.Stop(); err != nil {
+			s.logger.Warn("unable to stop spammer which didn't start in time", "err", err.Error())
+		}
+		return ErrStartTimeout
+	}
+}
+
+// Stop stops the spammer, it won't be restarted by its supervisor.
+// it also cancels a pending restart if the spammer already exited on its own.
+func (s *Spammer) Stop() error {
+	s.muSupervisor.Lock()
+	s.desired = false
+	s.cancelRestart()
+	s.muSupervisor.Unlock()
+	s.endRun()
+	if !s.IsRunning() {
+		return nil
+	}
+	return s.Spammer.Stop()
+}
+
+// cancelRestart must be called with muSupervisor held
+func (s *Spammer) cancelRestart() {
+	if s.pendingRestart != nil {
+		close(s.pendingRestart)
+		s.pendingRestart = nil
+	}
+}
+
+// Desired reports whether the spammer should be running, that is it was started and not stopped since.
+func (s *Spammer) Desired() bool {
+	return s.isDesired()
+}
+
+func (s *Spammer) isDesired() bool {
+	s.muSupervisor.Lock()
+	defer s.muSupervisor.Unlock()
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+	return s.desired
+}
+
+// supervise waits for the started spammer to exit and restarts it if it should still be running
+func (s *Spammer) supervise(exited <-chan error) {
+	err := <-exited
+	if !s.isDesired() {
+		if err != nil {
+			s.logger.Warn("spammer exited with an error", "err", err.Error())
+		}
+		return
+	}
+
+	msg := "spammer exited on its own"
+	data := map[string]interface{}{}
+	if err != nil {
+		data["err"] = err.Error()
+	}
+	s.emit(EventExited, msg, data)
+
+	s.muSupervisor.Lock()
+	cancel := make(chan struct{})
+	s.pendingRestart = cancel
+	s.muSupervisor.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		if !s.allowRestart() {
+			s.emit(EventRestartsCap, "spammer exhausted its restarts, giving up",
+				map[string]interface{}{"max_restarts": maxRestarts, "window": restartWindow.String()})
+			s.giveUp(cancel)
+			return
+		}
+
+		backoff := minBackoff << uint(attempt)
+		if backoff > maxBackoff || backoff <= 0 {
+			backoff = maxBackoff
+		}
+		s.emit(EventRestarting, "restarting spammer", map[string]interface{}{"backoff": backoff.String()})
+
+		select {
+		case <-time.After(backoff):
+		case <-cancel:
+			// stopped or started by hand in the meantime
+			return
+		case <-s.done:
+			return
+		}
+
+		restarted, err := s.restart(cancel)
+		if err != nil {
+			s.logger.Warn("unable to restart spammer", "err", err.Error())
+			continue
+		}
+		if restarted {
+			s.emit(EventRestarted, "spammer restarted", nil)
+		}
+		return
+	}
+}
+
+// restart starts the spammer again unless the pending restart was cancelled,
+// returns whether the spammer was restarted.
+func (s *Spammer) restart(cancel chan struct{}) (bool, error) {
+	s.muStart.Lock()
+	defer s.muStart.Unlock()
+	select {
+	case <-cancel:
+		return false, nil
+	default:
+	}
+	if !s.isDesired() {
+		return false, nil
+	}
+
+	if err := s.startWait(DefaultStartTimeout); err != nil {
+		return false, err
+	}
+	s.muSupervisor.Lock()
+	if s.pendingRestart == cancel {
+		s.pendingRestart = nil
+	}
+	s.muSupervisor.Unlock()
+	// stopped while it was starting
+	if !s.isDesired() {
+		if err := s.Spammer.Stop(); err != nil {
+			s.logger.Warn("unable to stop restarted spammer", "err", err.Error())
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// allowRestart records a restart if the cap of the current window isn't reached yet
+func (s *Spammer) allowRestart() bool {
+	s.muSupervisor.Lock()
+	defer s.muSupervisor.Unlock()
+	now := time.Now()
+	recent := s.restarts[:0]
+	for _, ts := range s.restarts {
+		if now.Sub(ts) < restartWindow {
+			recent = append(recent, ts)
+		}
+	}
+	s.restarts = recent
+	if len(s.restarts) >= maxRestarts {
+		return false
+	}
+	s.restarts = append(s.restarts, now)
+	return true
+}
+
+// giveUp marks the spammer as stopped on its own after it exhausted its restarts
+func (s *Spammer) giveUp(cancel chan struct{}) {
+	s.muSupervisor.Lock()
+	if s.pendingRestart != cancel {
+		// stopped or started by hand in the meantime
+		s.muSupervisor.Unlock()
+		return
+	}
+	s.pendingRestart = nil
+	s.desired = false
+	s.muSupervisor.Unlock()
+	s.endRun()
+
+	s.muStop.Lock()
+	s.stopReason = StopReasonCrashed
+	s.muStop.Unlock()
+	if s.OnAutoStop != nil {
+		s.OnAutoStop(StopReasonCrashed)
+	}
+}
@@ -0,0 +1,235 @@
+package spam
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iota-tangle-io/spamalot-slave/backend/models"
+)
+
+// SlaveName is the name of the slave used in templates, set on boot.
+var SlaveName = ""
+
+const randSuffixLength = 4
+
+// rendered lengths of the numeric placeholders, unix times have 10 digits until the year 2286
+const (
+	maxCounterLength = 20
+	maxUnixLength    = 10
+	hashLength       = 32
+)
+
+type placeholder struct {
+	render func(ctx *TemplateContext) string
+	// max length of the rendered value before it is encoded to trytes
+	maxLength func() int
+	// the rendered value already consists of trytes and is used as is
+	trytes bool
+}
+
+// placeholders usable in tag and message templates
+var placeholders = map[string]placeholder{
+	"name": {
+		func(ctx *TemplateContext) string { return ctx.Name },
+		func() int { return len(SlaveName) },
+		false,
+	},
+	"host": {
+		func(ctx *TemplateContext) string { return ctx.Host },
+		func() int { host, _ := os.Hostname(); return len(host) },
+		false,
+	},
+	"counter": {
+		func(ctx *TemplateContext) string { return strconv.FormatUint(ctx.Counter, 10) },
+		func() int { return maxCounterLength },
+		false,
+	},
+	"unix": {
+		func(ctx *TemplateContext) string { return strconv.FormatInt(ctx.Unix, 10) },
+		func() int { return maxUnixLength },
+		false,
+	},
+	"hash": {
+		func(ctx *TemplateContext) string { return ctx.ConfigHash },
+		func() int { return hashLength },
+		false,
+	},
+	"rand": {
+		func(ctx *TemplateContext) string { return randomTrytes(randSuffixLength) },
+		func() int { return randSuffixLength },
+		true,
+	},
+}
+
+// TemplateContext holds the values placeholders are rendered with.
+type TemplateContext struct {
+	Name       string
+	Host       string
+	Counter    uint64
+	Unix       int64
+	ConfigHash string
+}
+
+// Template is a tag or message containing placeholders like {name}.
+// templates are re-rendered after each issued transaction while the spammer already builds the next
+// bundle, so consecutive transactions may share a rendering: {counter} and {rand} are unique per
+// rendering, not per transaction.
+type Template struct {
+	// alternating literal text and placeholder names, starting with literal text
+	parts []string
+}
+
+// IsTemplate reports whether the given tag or message is a template instead of static trytes.
+func IsTemplate(s string) bool {
+	return strings.ContainsAny(s, "{}")
+}
+
+// ParseTemplate parses a template, placeholders are {name}, {host}, {counter}, {unix}, {hash} and {rand}.
+func ParseTemplate(s string) (*Template, error) {
+	t := &Template{}
+	for {
+		start := strings.IndexRune(s, '{')
+		if start == -1 {
+			if strings.ContainsRune(s, '}') {
+				return nil, fmt.Errorf("unmatched '}'")
+			}
+			t.parts = append(t.parts, s)
+			return t, nil
+		}
+		end := strings.IndexRune(s[start:], '}')
+		if end == -1 {
+			return nil, fmt.Errorf("unmatched '{'")
+		}
+		end += start
+		name := s[start+1 : end]
+		if _, ok := placeholders[name]; !ok {
+			return nil, fmt.Errorf("unknown placeholder {%s}", name)
+		}
+		if strings.ContainsRune(s[:start], '}') {
+			return nil, fmt.Errorf("unmatched '}'")
+		}
+		t.parts = append(t.parts, s[:start], name)
+		s = s[end+1:]
+	}
+}
+
+// Render renders the template and encodes it to trytes, placeholders rendering to trytes are kept as is.
+func (t *Template) Render(ctx *TemplateContext) string {
+	var rendered strings.Builder
+	for i, part := range t.parts {
+		if i%2 == 0 {
+			rendered.WriteString(ASCIIToTrytes(part))
+			continue
+		}
+		p := placeholders[part]
+		if p.trytes {
+			rendered.WriteString(p.render(ctx))
+			continue
+		}
+		rendered.WriteString(ASCIIToTrytes(p.render(ctx)))
+	}
+	return rendered.String()
+}
+
+// MaxLength returns the max length in trytes the template can render to.
+func (t *Template) MaxLength() int {
+	length := 0
+	for i, part := range t.parts {
+		if i%2 == 0 {
+			length += len(part) * 2
+			continue
+		}
+		p := placeholders[part]
+		if p.trytes {
+			length += p.maxLength()
+			continue
+		}
+		length += p.maxLength() * 2
+	}
+	return length
+}
+
+// ASCIIToTrytes encodes the given text to trytes, two trytes per byte.
+func ASCIIToTrytes(s string) string {
+	trytes := make([]byte, 0, len(s)*2)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		trytes = append(trytes, tryteAlphabet[c%27], tryteAlphabet[c/27%27])
+	}
+	return string(trytes)
+}
+
+// ConfigHash returns the hash of the given configuration as reported to the coordinator.
+func ConfigHash(config *models.SpammerConfig) (string, error) {
+	configBytes, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	hasher := md5.New()
+	hasher.Write(configBytes)
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func randomTrytes(n int) string {
+	trytes := make([]byte, n)
+	for i := range trytes {
+		trytes[i] = tryteAlphabet[rand.Intn(len(tryteAlphabet))]
+	}
+	return string(trytes)
+}
+
+// templates renders the tag and message templates of a spammer for each transaction
+type templates struct {
+	tag     *Template
+	message *Template
+	ctx     TemplateContext
+}
+
+// newTemplates returns nil if neither the tag nor the message of the config is a template
+func newTemplates(config *models.SpammerConfig) (*templates, error) {
+	if !IsTemplate(config.Tag) && !IsTemplate(config.Message) {
+		return nil, nil
+	}
+
+	t := &templates{}
+	var err error
+	if IsTemplate(config.Tag) {
+		if t.tag, err = ParseTemplate(config.Tag); err != nil {
+			return nil, err
+		}
+	}
+	if IsTemplate(config.Message) {
+		if t.message, err = ParseTemplate(config.Message); err != nil {
+			return nil, err
+		}
+	}
+
+	t.ctx.Name = SlaveName
+	t.ctx.Host, _ = os.Hostname()
+	if t.ctx.ConfigHash, err = ConfigHash(config); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// render returns the tag and message for the transactions following the given amount of issued ones,
+// static values are kept as is. templates are validated to never render beyond the max tag and message length.
+func (t *templates) render(config *models.SpammerConfig, issued uint64) (tag string, message string) {
+	t.ctx.Unix = time.Now().Unix()
+	t.ctx.Counter = issued
+	tag, message = config.Tag, config.Message
+	if t.tag != nil {
+		tag = t.tag.Render(&t.ctx)
+	}
+	if t.message != nil {
+		message = t.message.Render(&t.ctx)
+	}
+	return tag, message
+}
@@ -0,0 +1,118 @@
+package spam
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		parts    []string
+		err      string
+	}{
+		{"SPAM", []string{"SPAM"}, ""},
+		{"{name}", []string{"", "name", ""}, ""},
+		{"tx {counter} of {name}", []string{"tx ", "counter", " of ", "name", ""}, ""},
+		{"{unix}{rand}", []string{"", "unix", "", "rand", ""}, ""},
+		{"{hash", nil, "unmatched '{'"},
+		{"hash}", nil, "unmatched '}'"},
+		{"} {name}", nil, "unmatched '}'"},
+		{"{name}}", nil, "unmatched '}'"},
+		{"{}", nil, "unknown placeholder {}"},
+		{"{seed}", nil, "unknown placeholder {seed}"},
+	}
+
+	for _, test := range tests {
+		tmpl, err := ParseTemplate(test.template)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%q: expected error %q, got %v", test.template, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", test.template, err)
+			continue
+		}
+		if strings.Join(tmpl.parts, "|") != strings.Join(test.parts, "|") {
+			t.Errorf("%q: expected parts %q, got %q", test.template, test.parts, tmpl.parts)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	ctx := &TemplateContext{Name: "slave1", Host: "host", Counter: 42, Unix: 1528000000, ConfigHash: "abc"}
+	tests := []struct {
+		template string
+		rendered string
+	}{
+		{"{name}", "slave1"},
+		{"{name}@{host}", "slave1@host"},
+		{"#{counter} {unix}", "#42 1528000000"},
+		{"{hash}", "abc"},
+	}
+
+	for _, test := range tests {
+		tmpl, err := ParseTemplate(test.template)
+		if err != nil {
+			t.Fatalf("%q: %v", test.template, err)
+		}
+		rendered := tmpl.Render(ctx)
+		if expected := ASCIIToTrytes(test.rendered); rendered != expected {
+			t.Errorf("%q: expected %s, got %s", test.template, expected, rendered)
+		}
+		if !isTrytes(rendered) {
+			t.Errorf("%q: rendered %s isn't trytes", test.template, rendered)
+		}
+	}
+
+	// the random suffix already consists of trytes and isn't encoded again
+	tmpl, _ := ParseTemplate("{rand}")
+	if rendered := tmpl.Render(ctx); len(rendered) != randSuffixLength || !isTrytes(rendered) {
+		t.Errorf("expected %d trytes, got %s", randSuffixLength, rendered)
+	}
+	tmpl, _ = ParseTemplate("#{rand}")
+	if rendered := tmpl.Render(ctx); len(rendered) != 2+randSuffixLength || rendered[:2] != ASCIIToTrytes("#") {
+		t.Errorf("expected the encoded literal followed by %d trytes, got %s", randSuffixLength, rendered)
+	}
+}
+
+func TestMaxLength(t *testing.T) {
+	previous := SlaveName
+	SlaveName = "slave1"
+	defer func() { SlaveName = previous }()
+
+	tests := []struct {
+		template  string
+		maxLength int
+	}{
+		{"AB", 4},
+		{"{name}", 12},
+		{"{counter}", maxCounterLength * 2},
+		{"{unix}", maxUnixLength * 2},
+		{"{hash}", 64},
+		{"{rand}", randSuffixLength},
+		{"#{name}{rand}", 2 + 12 + randSuffixLength},
+	}
+
+	for _, test := range tests {
+		tmpl, err := ParseTemplate(test.template)
+		if err != nil {
+			t.Fatalf("%q: %v", test.template, err)
+		}
+		if maxLength := tmpl.MaxLength(); maxLength != test.maxLength {
+			t.Errorf("%q: expected max length %d, got %d", test.template, test.maxLength, maxLength)
+		}
+	}
+
+	// the config hash placeholder renders to its max length
+	hash, err := ConfigHash(validConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, _ := ParseTemplate("{hash}")
+	if rendered := tmpl.Render(&TemplateContext{ConfigHash: hash}); len(rendered) != tmpl.MaxLength() {
+		t.Errorf("expected {hash} to render to %d trytes, got %d", tmpl.MaxLength(), len(rendered))
+	}
+}
@@ -0,0 +1,57 @@
+package spam
+
+import (
+	"github.com/iota-tangle-io/iota-spamalot.go"
+	"github.com/iota-tangle-io/spamalot-slave/backend/txlog"
+	"time"
+)
+
+// updateConfigHash recomputes the config hash transactions are logged with
+func (s *Spammer) updateConfigHash() {
+	hash, err := ConfigHash(s.Config)
+	if err != nil {
+		s.logger.Warn("unable to hash config", "err", err.Error())
+	}
+	s.muTxLog.Lock()
+	s.configHash = hash
+	s.muTxLog.Unlock()
+}
+
+// logTx appends the transaction of the given metric to the tx log if one is set
+// and hands it to the confirmation tracker. transactions of a dry-run aren't logged as they were never sent.
+func (s *Spammer) logTx(metric spamalot.Metric) {
+	s.muTxLog.Lock()
+	log, configHash := s.txLog, s.configHash
+	s.muTxLog.Unlock()
+	if s.Config.DryRun {
+		log = nil
+	}
+	if log == nil && s.confirmer == nil {
+		return
+	}
+
+	tx := TXData{}
+	if err := DecodeData(metric.Data, &tx); err != nil {
+		s.logger.Warn("unable to decode tx metric", "err", err.Error())
+		return
+	}
+	if s.confirmer != nil {
+		s.confirmer.track(tx.Hash, time.Now())
+	}
+	if log == nil {
+		return
+	}
+	entry := txlog.Entry{
+		TS: time.Now(), Hash: tx.Hash, Node: s.Config.NodeAddress, ConfigHash: configHash, Spammer: s.Name(),
+	}
+	if err := log.Append(entry); err != nil {
+		s.logger.Warn("unable to log tx", "hash", tx.Hash, "err", err.Error())
+	}
+}
+
+// SetTxLog makes the spammer append each sent transaction to the given log.
+func (s *Spammer) SetTxLog(log *txlog.Log) {
+	s.muTxLog.Lock()
+	s.txLog = log
+	s.muTxLog.Unlock()
+}
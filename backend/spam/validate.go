@@ -0,0 +1,197 @@
+package spam
+
+import (
+	"fmt"
+	"github.com/CWarner818/giota"
+	"github.com/iota-tangle-io/spamalot-coo/api"
+	"github.com/iota-tangle-io/spamalot-slave/backend/models"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	MinMWM = 1
+	// beyond this a single PoW takes impractically long
+	MaxMWM   = 20
+	MinDepth = 1
+	MaxDepth = 15
+
+	MinSecurityLvl = 1
+	MaxSecurityLvl = 3
+
+	MinNiceness = -20
+	MaxNiceness = 19
+
+	tryteAlphabet   = "9ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	addressLength   = 81
+	checksumLength  = 9
+	maxTagLength    = 27
+	maxMessageTryte = 2187
+)
+
+// FieldError describes why the value of a configuration field is invalid.
+type FieldError struct {
+	Field string `json:"field"`
+	Msg   string `json:"msg"`
+}
+
+// ValidationErrors are all field errors of a configuration.
+type ValidationErrors []FieldError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = fmt.Sprintf("%s: %s", err.Field, err.Msg)
+	}
+	return "invalid spammer configuration, " + strings.Join(msgs, "; ")
+}
+
+func (errs *ValidationErrors) add(field string, format string, args ...interface{}) {
+	*errs = append(*errs, FieldError{Field: field, Msg: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks the given configuration and returns ValidationErrors if any field is invalid.
+func Validate(config *models.SpammerConfig) error {
+	errs := ValidationErrors{}
+
+	if !config.DryRun || config.NodeAddress != "" {
+		if u, err := url.Parse(config.NodeAddress); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs.add("node_address", "must be a http(s) URL")
+		}
+	}
+	if config.MWM < MinMWM || config.MWM > MaxMWM {
+		errs.add("mwm", "must be between %d and %d", MinMWM, MaxMWM)
+	}
+	if config.Depth < MinDepth || config.Depth > MaxDepth {
+		errs.add("depth", "must be between %d and %d", MinDepth, MaxDepth)
+	}
+	if config.SecurityLvl < MinSecurityLvl || config.SecurityLvl > MaxSecurityLvl {
+		errs.add("security_lvl", "must be between %d and %d", MinSecurityLvl, MaxSecurityLvl)
+	}
+	if len(config.DestAddresses) == 0 && config.DestSeed == "" {
+		if err := validateAddress(config.DestAddress); err != nil {
+			errs.add("dest_address", "%s", err.Error())
+		}
+	}
+	for i, address := range config.DestAddresses {
+		if err := validateAddress(address); err != nil {
+			errs.add(fmt.Sprintf("dest_addresses[%d]", i), "%s", err.Error())
+		}
+	}
+	if config.DestSeed != "" {
+		if !isTrytes(config.DestSeed) || len(config.DestSeed) != addressLength {
+			errs.add("dest_seed", "must be %d trytes", addressLength)
+		}
+		if config.DestCount < 0 || config.DestCount > maxDestCount {
+			errs.add("dest_count", "must be between 0 and %d", maxDestCount)
+		}
+	}
+	switch config.Rotation {
+	case "", RotationRoundRobin, RotationRandom:
+	case RotationPerN:
+		if config.RotateEvery < 1 {
+			errs.add("rotate_every", "must be at least 1 with the %s rotation", RotationPerN)
+		}
+	default:
+		errs.add("rotation", "must be one of %s, %s or %s", RotationRoundRobin, RotationRandom, RotationPerN)
+	}
+	if IsTemplate(config.Tag) {
+		validateTemplate(&errs, "tag", config.Tag, maxTagLength)
+	} else if !isTrytes(config.Tag) || len(config.Tag) > maxTagLength {
+		errs.add("tag", "must be at most %d trytes or a template", maxTagLength)
+	}
+	if IsTemplate(config.Message) {
+		validateTemplate(&errs, "message", config.Message, maxMessageTryte)
+	} else if !isTrytes(config.Message) || len(config.Message) > maxMessageTryte {
+		errs.add("message", "must be at most %d trytes or a template", maxMessageTryte)
+	}
+	if config.TargetTPS < 0 {
+		errs.add("target_tps", "must not be negative")
+	}
+	if config.Burst < 0 {
+		errs.add("burst", "must not be negative")
+	}
+	switch config.PoWMode {
+	case api.POW_LOCAL, api.POW_REMOTE:
+	default:
+		errs.add("pow_mode", "must be %d (%s) or %d (%s)", api.POW_LOCAL, PoWLocal, api.POW_REMOTE, PoWRemote)
+	}
+	if config.HybridPoW && config.PoWMode == api.POW_LOCAL {
+		errs.add("hybrid_pow", "requires remote PoW")
+	}
+	if config.PoWThreads < 0 {
+		errs.add("pow_threads", "must not be negative")
+	}
+	if config.MaxProcs < 0 {
+		errs.add("max_procs", "must not be negative")
+	}
+	if config.StopAfterTxs < 0 {
+		errs.add("stop_after_txs", "must not be negative")
+	}
+	if config.StopAfterSeconds < 0 {
+		errs.add("stop_after_seconds", "must not be negative")
+	}
+	if config.StopAt != nil && config.StopAt.Before(time.Now()) {
+		errs.add("stop_at", "must be in the future")
+	}
+	if config.StopErrorRate < 0 {
+		errs.add("stop_error_rate", "must not be negative")
+	}
+	if config.StopErrorRateSeconds < 0 {
+		errs.add("stop_error_rate_seconds", "must not be negative")
+	}
+	if adaptive := config.Adaptive; adaptive != nil {
+		if adaptive.MinDepth < MinDepth || adaptive.MaxDepth > MaxDepth || adaptive.MinDepth > adaptive.MaxDepth {
+			errs.add("adaptive", "depth bounds must be within %d and %d with min_depth <= max_depth", MinDepth, MaxDepth)
+		}
+		if adaptive.MaxBadTipRate <= 0 {
+			errs.add("adaptive", "max_bad_tip_rate must be positive")
+		}
+	}
+	if config.Niceness < MinNiceness || config.Niceness > MaxNiceness {
+		errs.add("niceness", "must be between %d and %d", MinNiceness, MaxNiceness)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateTemplate checks that the template parses and can't render beyond the given length
+func validateTemplate(errs *ValidationErrors, field string, s string, maxLength int) {
+	t, err := ParseTemplate(s)
+	if err != nil {
+		errs.add(field, "invalid template, %s", err.Error())
+		return
+	}
+	if length := t.MaxLength(); length > maxLength {
+		errs.add(field, "template renders to up to %d trytes, at most %d are allowed", length, maxLength)
+	}
+}
+
+func isTrytes(s string) bool {
+	for _, c := range s {
+		if !strings.ContainsRune(tryteAlphabet, c) {
+			return false
+		}
+	}
+	return true
+}
+
+func validateAddress(address string) error {
+	if !isTrytes(address) {
+		return fmt.Errorf("must only contain trytes")
+	}
+	switch len(address) {
+	case addressLength:
+		return nil
+	case addressLength + checksumLength:
+		if giota.Address(address[:addressLength]).Checksum() != giota.Trytes(address[addressLength:]) {
+			return fmt.Errorf("has an invalid checksum")
+		}
+		return nil
+	}
+	return fmt.Errorf("must be %d trytes or %d trytes with checksum", addressLength, addressLength+checksumLength)
+}
@@ -0,0 +1,139 @@
+package spam
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iota-tangle-io/spamalot-coo/api"
+	"github.com/iota-tangle-io/spamalot-slave/backend/models"
+)
+
+var testAddress = strings.Repeat("A", addressLength)
+
+func validConfig() *models.SpammerConfig {
+	return &models.SpammerConfig{
+		SpammerConfig: api.SpammerConfig{
+			NodeAddress: "http://localhost:14265",
+			MWM:         14,
+			Depth:       3,
+			DestAddress: testAddress,
+			PoWMode:     api.POW_REMOTE,
+			Tag:         "SPAMALOT",
+			Message:     "",
+			SecurityLvl: 2,
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name   string
+		modify func(config *models.SpammerConfig)
+		// fields expected to be invalid, none if the config is valid
+		fields []string
+	}{
+		{"valid", func(config *models.SpammerConfig) {}, nil},
+		{"dry-run without node", func(config *models.SpammerConfig) {
+			config.DryRun = true
+			config.NodeAddress = ""
+		}, nil},
+		{"missing node", func(config *models.SpammerConfig) { config.NodeAddress = "" }, []string{"node_address"}},
+		{"node without scheme", func(config *models.SpammerConfig) { config.NodeAddress = "localhost:14265" }, []string{"node_address"}},
+		{"mwm too low", func(config *models.SpammerConfig) { config.MWM = MinMWM - 1 }, []string{"mwm"}},
+		{"mwm too high", func(config *models.SpammerConfig) { config.MWM = MaxMWM + 1 }, []string{"mwm"}},
+		{"depth too high", func(config *models.SpammerConfig) { config.Depth = MaxDepth + 1 }, []string{"depth"}},
+		{"security lvl", func(config *models.SpammerConfig) { config.SecurityLvl = 4 }, []string{"security_lvl"}},
+		{"address too short", func(config *models.SpammerConfig) { config.DestAddress = "ABC" }, []string{"dest_address"}},
+		{"address not trytes", func(config *models.SpammerConfig) {
+			config.DestAddress = strings.Repeat("a", addressLength)
+		}, []string{"dest_address"}},
+		{"dest addresses replace dest address", func(config *models.SpammerConfig) {
+			config.DestAddress = ""
+			config.DestAddresses = []string{testAddress, testAddress}
+		}, nil},
+		{"invalid dest addresses", func(config *models.SpammerConfig) {
+			config.DestAddresses = []string{testAddress, "ABC"}
+		}, []string{"dest_addresses[1]"}},
+		{"dest seed too short", func(config *models.SpammerConfig) {
+			config.DestAddress = ""
+			config.DestSeed = "ABC"
+			config.DestCount = 1
+		}, []string{"dest_seed"}},
+		{"unknown rotation", func(config *models.SpammerConfig) { config.Rotation = "sometimes" }, []string{"rotation"}},
+		{"per-n without n", func(config *models.SpammerConfig) { config.Rotation = RotationPerN }, []string{"rotate_every"}},
+		{"tag too long", func(config *models.SpammerConfig) { config.Tag = strings.Repeat("A", maxTagLength+1) }, []string{"tag"}},
+		{"tag not trytes", func(config *models.SpammerConfig) { config.Tag = "spamalot" }, []string{"tag"}},
+		{"message too long", func(config *models.SpammerConfig) {
+			config.Message = strings.Repeat("A", maxMessageTryte+1)
+		}, []string{"message"}},
+		{"tag template", func(config *models.SpammerConfig) { config.Tag = "{rand}" }, nil},
+		{"invalid tag template", func(config *models.SpammerConfig) { config.Tag = "{seed}" }, []string{"tag"}},
+		{"tag template too long", func(config *models.SpammerConfig) { config.Tag = "{hash}" }, []string{"tag"}},
+		{"tag template with literal text too long", func(config *models.SpammerConfig) {
+			config.Tag = "SPAMALOT{unix}"
+		}, []string{"tag"}},
+		{"message template", func(config *models.SpammerConfig) {
+			config.Message = "{name}@{host} #{counter} {unix} {hash} {rand}"
+		}, nil},
+		{"message template too long", func(config *models.SpammerConfig) {
+			config.Message = strings.Repeat("{hash}", maxMessageTryte/64+1)
+		}, []string{"message"}},
+		{"negative rate", func(config *models.SpammerConfig) {
+			config.TargetTPS = -1
+			config.Burst = -1
+		}, []string{"target_tps", "burst"}},
+		{"local pow", func(config *models.SpammerConfig) { config.PoWMode = api.POW_LOCAL }, nil},
+		{"unknown pow mode", func(config *models.SpammerConfig) { config.PoWMode = 42 }, []string{"pow_mode"}},
+		{"hybrid pow", func(config *models.SpammerConfig) { config.HybridPoW = true }, nil},
+		{"hybrid pow with local pow", func(config *models.SpammerConfig) {
+			config.HybridPoW = true
+			config.PoWMode = api.POW_LOCAL
+		}, []string{"hybrid_pow"}},
+		{"negative limits", func(config *models.SpammerConfig) {
+			config.PoWThreads = -1
+			config.MaxProcs = -1
+		}, []string{"pow_threads", "max_procs"}},
+		{"niceness", func(config *models.SpammerConfig) { config.Niceness = MaxNiceness + 1 }, []string{"niceness"}},
+		{"stop at in the past", func(config *models.SpammerConfig) { config.StopAt = &past }, []string{"stop_at"}},
+		{"several fields", func(config *models.SpammerConfig) {
+			config.MWM = 0
+			config.Depth = 0
+		}, []string{"mwm", "depth"}},
+	}
+
+	for _, test := range tests {
+		config := validConfig()
+		test.modify(config)
+		err := Validate(config)
+		if len(test.fields) == 0 {
+			if err != nil {
+				t.Errorf("%s: expected a valid config, got %v", test.name, err)
+			}
+			continue
+		}
+
+		errs, ok := err.(ValidationErrors)
+		if !ok {
+			t.Errorf("%s: expected validation errors, got %v", test.name, err)
+			continue
+		}
+		var fields []string
+		for _, fieldErr := range errs {
+			fields = append(fields, fieldErr.Field)
+		}
+		if strings.Join(fields, ",") != strings.Join(test.fields, ",") {
+			t.Errorf("%s: expected invalid fields %v, got %v", test.name, test.fields, fields)
+		}
+	}
+}
+
+func TestUnknownPoWMode(t *testing.T) {
+	config := validConfig()
+	config.PoWMode = 42
+	err := Validate(config)
+	if err == nil || !strings.Contains(err.Error(), "0 (local) or 1 (remote)") {
+		t.Errorf("expected the valid pow modes to be listed, got %v", err)
+	}
+}
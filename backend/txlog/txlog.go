@@ -0,0 +1,270 @@
+package txlog
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	currentFile   = "txs.jsonl"
+	rotatedLayout = "20060102T150405.000000000"
+
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// Entry is a transaction sent by a spammer.
+type Entry struct {
+	TS         time.Time `json:"ts"`
+	Hash       string    `json:"hash"`
+	Node       string    `json:"node"`
+	ConfigHash string    `json:"config_hash"`
+	Spammer    string    `json:"spammer"`
+}
+
+var csvHeader = []string{"ts", "hash", "node", "config_hash", "spammer"}
+
+func (entry *Entry) record() []string {
+	return []string{entry.TS.UTC().Format(time.RFC3339Nano), entry.Hash, entry.Node, entry.ConfigHash, entry.Spammer}
+}
+
+// Log is an append-only log of sent transactions in JSON lines.
+// the current file is rotated once it exceeds the max size and only the newest rotated files are kept.
+type Log struct {
+	dir      string
+	maxSize  int64
+	maxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Open opens the log in the given directory, creating it if needed.
+func Open(dir string, maxSize int64, maxFiles int) (*Log, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	log := &Log{dir: dir, maxSize: maxSize, maxFiles: maxFiles}
+	if err := log.open(); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+func (log *Log) open() error {
+	file, err := os.OpenFile(filepath.Join(log.dir, currentFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	log.file, log.size = file, info.Size()
+	return nil
+}
+
+// Append adds the given entry to the log.
+func (log *Log) Append(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	if log.file == nil {
+		return fmt.Errorf("tx log is closed")
+	}
+	// the entry is still appended if rotating failed but the file could be reopened
+	var rotateErr error
+	if log.size > 0 && log.size+int64(len(line)) > log.maxSize {
+		if rotateErr = log.rotate(); log.file == nil {
+			return rotateErr
+		}
+	}
+	n, err := log.file.Write(line)
+	log.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return rotateErr
+}
+
+// rotate moves the current file aside and deletes the oldest rotated files beyond the max count
+func (log *Log) rotate() error {
+	if err := log.file.Close(); err != nil {
+		return err
+	}
+	log.file = nil
+	rotated := fmt.Sprintf("txs-%s.jsonl", time.Now().UTC().Format(rotatedLayout))
+	if err := os.Rename(filepath.Join(log.dir, currentFile), filepath.Join(log.dir, rotated)); err != nil {
+		// keep appending to the current file
+		if openErr := log.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := log.open(); err != nil {
+		return err
+	}
+	files, err := log.rotated()
+	if err != nil {
+		return err
+	}
+	for len(files) > log.maxFiles {
+		if err := os.Remove(filepath.Join(log.dir, files[0])); err != nil {
+			return err
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+// rotated returns the names of the rotated files from oldest to newest
+func (log *Log) rotated() ([]string, error) {
+	infos, err := ioutil.ReadDir(log.dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, info := range infos {
+		name := info.Name()
+		if strings.HasPrefix(name, "txs-") && strings.HasSuffix(name, ".jsonl") {
+			files = append(files, name)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Export writes the entries within [from, to] in the given format, either csv or jsonl, from oldest to newest.
+func (log *Log) Export(w io.Writer, format string, from time.Time, to time.Time) error {
+	if format != FormatCSV && format != FormatJSONL {
+		return fmt.Errorf("unknown export format %s", format)
+	}
+
+	readers, err := log.snapshot()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, r := range readers {
+			r.Close()
+		}
+	}()
+
+	var csvWriter *csv.Writer
+	if format == FormatCSV {
+		csvWriter = csv.NewWriter(w)
+		if err := csvWriter.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range readers {
+		if err := scan(r, func(line []byte, entry *Entry) error {
+			if entry.TS.Before(from) || entry.TS.After(to) {
+				return nil
+			}
+			if csvWriter != nil {
+				return csvWriter.Write(entry.record())
+			}
+			_, err := w.Write(append(line, '\n'))
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+
+	if csvWriter != nil {
+		csvWriter.Flush()
+		return csvWriter.Error()
+	}
+	return nil
+}
+
+// snapshotReader reads a file of the log up to its size at the time of the snapshot
+type snapshotReader struct {
+	io.Reader
+	file *os.File
+}
+
+func (r *snapshotReader) Close() error {
+	return r.file.Close()
+}
+
+// snapshot opens all files of the log from oldest to newest, so that they can be read without
+// holding the lock while appending and rotating go on. the current file is only read up to its
+// size at the time of the snapshot.
+func (log *Log) snapshot() ([]*snapshotReader, error) {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	files, err := log.rotated()
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, currentFile)
+
+	var readers []*snapshotReader
+	for _, name := range files {
+		file, err := os.Open(filepath.Join(log.dir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			for _, r := range readers {
+				r.Close()
+			}
+			return nil, err
+		}
+		var r io.Reader = file
+		if name == currentFile {
+			r = io.LimitReader(file, log.size)
+		}
+		readers = append(readers, &snapshotReader{Reader: r, file: file})
+	}
+	return readers, nil
+}
+
+// scan calls fn for each entry read from r, corrupt lines are skipped
+func scan(r io.Reader, fn func(line []byte, entry *Entry) error) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		entry := &Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			continue
+		}
+		if err := fn(scanner.Bytes(), entry); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Close closes the current file of the log.
+func (log *Log) Close() error {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	if log.file == nil {
+		return nil
+	}
+	err := log.file.Close()
+	log.file = nil
+	return err
+}
@@ -0,0 +1,164 @@
+package txlog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestLog(t *testing.T, maxSize int64, maxFiles int) (*Log, string) {
+	dir, err := ioutil.TempDir("", "txlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log, err := Open(dir, maxSize, maxFiles)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return log, dir
+}
+
+func testEntry(i int, ts time.Time) Entry {
+	return Entry{TS: ts, Hash: fmt.Sprintf("HASH%05d", i), Node: "http://localhost:14265", ConfigHash: "abc", Spammer: "dashboard"}
+}
+
+func TestRotation(t *testing.T) {
+	log, dir := openTestLog(t, 512, 2)
+	defer os.RemoveAll(dir)
+	defer log.Close()
+
+	start := time.Now()
+	for i := 0; i < 50; i++ {
+		if err := log.Append(testEntry(i, start.Add(time.Duration(i)*time.Millisecond))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rotated, err := log.rotated()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) != 2 {
+		t.Fatalf("expected 2 rotated files to be kept, got %d", len(rotated))
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range infos {
+		if info.Size() > 512 {
+			t.Errorf("%s exceeds the max size with %d bytes", info.Name(), info.Size())
+		}
+	}
+
+	// the newest entries survive the pruning, in order
+	buf := &bytes.Buffer{}
+	if err := log.Export(buf, FormatJSONL, start, start.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) == 0 || len(lines) >= 50 {
+		t.Fatalf("expected the oldest entries to be pruned, got %d entries", len(lines))
+	}
+	if !strings.Contains(lines[len(lines)-1], "HASH00049") {
+		t.Errorf("expected the newest entry last, got %s", lines[len(lines)-1])
+	}
+}
+
+func TestExport(t *testing.T) {
+	log, dir := openTestLog(t, 1<<20, 5)
+	defer os.RemoveAll(dir)
+	defer log.Close()
+
+	start := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		if err := log.Append(testEntry(i, start.Add(time.Duration(i)*time.Minute))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := log.Export(buf, FormatCSV, start.Add(2*time.Minute), start.Add(4*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("expected a header and 3 entries, got %d records", len(records))
+	}
+	if strings.Join(records[0], ",") != strings.Join(csvHeader, ",") {
+		t.Errorf("unexpected header %v", records[0])
+	}
+	if records[1][1] != "HASH00002" || records[3][1] != "HASH00004" {
+		t.Errorf("unexpected entries %v", records[1:])
+	}
+
+	buf.Reset()
+	if err := log.Export(buf, "xml", start, start.Add(time.Hour)); err == nil {
+		t.Error("expected an unknown format to be rejected")
+	}
+}
+
+// appending must go on while an export is streaming to a slow reader
+func TestExportDoesNotBlockAppend(t *testing.T) {
+	log, dir := openTestLog(t, 1<<20, 5)
+	defer os.RemoveAll(dir)
+	defer log.Close()
+
+	start := time.Now()
+	if err := log.Append(testEntry(0, start)); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &blockingWriter{written: make(chan struct{}), release: make(chan struct{})}
+	exported := make(chan error, 1)
+	go func() {
+		exported <- log.Export(w, FormatJSONL, start.Add(-time.Hour), start.Add(time.Hour))
+	}()
+	<-w.written
+
+	appended := make(chan error, 1)
+	go func() {
+		appended <- log.Append(testEntry(1, start))
+	}()
+	select {
+	case err := <-appended:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("append blocked by the export")
+	}
+
+	close(w.release)
+	if err := <-exported; err != nil {
+		t.Fatal(err)
+	}
+	// only the entries present when the export began are exported
+	if w.lines != 1 {
+		t.Errorf("expected 1 exported entry, got %d", w.lines)
+	}
+}
+
+type blockingWriter struct {
+	written chan struct{}
+	release chan struct{}
+	lines   int
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.lines++
+	if w.lines == 1 {
+		close(w.written)
+		<-w.release
+	}
+	return len(p), nil
+}
@@ -1,34 +1,282 @@
 package utilities
 
 import (
-	"os"
-	"gopkg.in/inconshreveable/log15.v2"
 	"fmt"
 	"github.com/mattn/go-colorable"
+	"gopkg.in/inconshreveable/log15.v2"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
 )
 
 var Debug = false
 
-func init() {
-	os.Mkdir("./logs", 0777)
+const (
+	LogFormatLogfmt = "logfmt"
+	LogFormatJSON   = "json"
+)
+
+// LogConfig defines where and how the loggers write.
+type LogConfig struct {
+	// format of the log records, logfmt or json, defaults to logfmt with colored terminal output
+	Format string
+	// only log to stdout, e.g. when running in a container
+	StdoutOnly bool
+	// directory of the log files, defaults to ./logs
+	Dir string
+	// size in MB at which a log file is rotated, defaults to 50
+	MaxSizeMB int
+	// age in hours at which a log file is rotated, 0 disables rotation by age
+	MaxAgeHours int
+	// rotated files kept per component, defaults to 5
+	MaxFiles int
+}
+
+var (
+	muLogging sync.Mutex
+	logConfig = LogConfig{}
+	// one logger per component sharing the stdout handler
+	loggers = map[string]log15.Logger{}
+	// log files are opened once per component and kept across reconfigurations
+	logFiles      = map[string]*rotatingFile{}
+	stdoutHandler log15.Handler
+)
+
+// ConfigureLogging applies the given configuration to all existing and future loggers.
+func ConfigureLogging(config LogConfig) error {
+	if config.Format == "" {
+		config.Format = LogFormatLogfmt
+	}
+	if config.Format != LogFormatLogfmt && config.Format != LogFormatJSON {
+		return fmt.Errorf("unknown log format %s", config.Format)
+	}
+	if config.Dir == "" {
+		config.Dir = "./logs"
+	}
+	if config.MaxSizeMB <= 0 {
+		config.MaxSizeMB = 50
+	}
+	if config.MaxFiles <= 0 {
+		config.MaxFiles = 5
+	}
+
+	muLogging.Lock()
+	defer muLogging.Unlock()
+	if !config.StdoutOnly {
+		if err := os.MkdirAll(config.Dir, 0777); err != nil {
+			return err
+		}
+	}
+	logConfig = config
+	stdoutHandler = nil
+	for name, file := range logFiles {
+		file.Close()
+		delete(logFiles, name)
+	}
+	for name, logger := range loggers {
+		handler, err := newHandler(name)
+		if err != nil {
+			return err
+		}
+		logger.SetHandler(handler)
+	}
+	return nil
 }
 
+// GetLogger returns the logger of the given component, loggers are created once per component.
 func GetLogger(name string) (log15.Logger, error) {
+	muLogging.Lock()
+	defer muLogging.Unlock()
+	if logger, ok := loggers[name]; ok {
+		return logger, nil
+	}
 
-	// open a new logfile
-	fileHandler, err := log15.FileHandler(fmt.Sprintf("./logs/%s.log", name), log15.LogfmtFormat())
+	handler, err := newHandler(name)
 	if err != nil {
 		return nil, err
 	}
-
-	handler := log15.MultiHandler(
-		fileHandler,
-		log15.StreamHandler(colorable.NewColorableStdout(), log15.TerminalFormat()),
-	)
-	if !Debug {
-		handler = log15.LvlFilterHandler(log15.LvlInfo, handler)
-	}
 	logger := log15.New("comp", name)
 	logger.SetHandler(handler)
+	loggers[name] = logger
 	return logger, nil
 }
+
+// OpenLogFile opens a log file of the given name in the log directory which is rotated like the
+// component logs, e.g. for the request log. stdout is returned if only stdout is logged to.
+func OpenLogFile(name string) (io.Writer, error) {
+	muLogging.Lock()
+	defer muLogging.Unlock()
+	if err := defaultLogConfig(); err != nil {
+		return nil, err
+	}
+	if logConfig.StdoutOnly {
+		return os.Stdout, nil
+	}
+	return openLogFile(name)
+}
+
+// must be called with muLogging held
+func openLogFile(name string) (*rotatingFile, error) {
+	return openRotatingFile(filepath.Join(logConfig.Dir, name+".log"),
+		int64(logConfig.MaxSizeMB)<<20, time.Duration(logConfig.MaxAgeHours)*time.Hour, logConfig.MaxFiles)
+}
+
+// defaultLogConfig uses the defaults if logging isn't configured yet, must be called with muLogging held
+func defaultLogConfig() error {
+	if logConfig.Format != "" {
+		return nil
+	}
+	logConfig.Format = LogFormatLogfmt
+	logConfig.Dir = "./logs"
+	logConfig.MaxSizeMB = 50
+	logConfig.MaxFiles = 5
+	return os.MkdirAll(logConfig.Dir, 0777)
+}
+
+// newHandler must be called with muLogging held
+func newHandler(name string) (log15.Handler, error) {
+	if err := defaultLogConfig(); err != nil {
+		return nil, err
+	}
+
+	if stdoutHandler == nil {
+		if logConfig.Format == LogFormatJSON {
+			stdoutHandler = log15.StreamHandler(os.Stdout, log15.JsonFormat())
+		} else {
+			stdoutHandler = log15.StreamHandler(colorable.NewColorableStdout(), log15.TerminalFormat())
+		}
+	}
+
+	handler := stdoutHandler
+	if !logConfig.StdoutOnly {
+		file, ok := logFiles[name]
+		if !ok {
+			var err error
+			if file, err = openLogFile(name); err != nil {
+				return nil, err
+			}
+			logFiles[name] = file
+		}
+		format := log15.LogfmtFormat()
+		if logConfig.Format == LogFormatJSON {
+			format = log15.JsonFormat()
+		}
+		handler = log15.MultiHandler(log15.StreamHandler(file, format), stdoutHandler)
+	}
+
+	return levelFilterHandler(name, handler), nil
+}
+
+// LogComponents are the components of the slave whose level can be changed,
+// also before their logger is created, e.g. the slave's before connecting to the coordinator.
+var LogComponents = []string{"app", "slave", "spammer", "spammer-router"}
+
+var (
+	muLevels sync.RWMutex
+	// levels set at runtime per component
+	levels = map[string]log15.Lvl{}
+	// level of the components without an own level, derived from Debug if not set
+	defaultLevel *log15.Lvl
+)
+
+// levelFilterHandler drops the records above the current level of the given component
+func levelFilterHandler(name string, h log15.Handler) log15.Handler {
+	return log15.FuncHandler(func(r *log15.Record) error {
+		if r.Lvl > LogLevel(name) {
+			return nil
+		}
+		return h.Log(r)
+	})
+}
+
+// LogLevel returns the current level of the given component.
+func LogLevel(name string) log15.Lvl {
+	muLevels.RLock()
+	defer muLevels.RUnlock()
+	if lvl, ok := levels[name]; ok {
+		return lvl
+	}
+	return defaultLogLevel()
+}
+
+// must be called with muLevels held
+func defaultLogLevel() log15.Lvl {
+	if defaultLevel != nil {
+		return *defaultLevel
+	}
+	if Debug {
+		return log15.LvlDebug
+	}
+	return log15.LvlInfo
+}
+
+// SetLogLevel changes the level of the given component at runtime, an empty component sets the default level.
+// the level is one of debug, info, warn, error and crit, the component must be one of LogComponents
+// or have a logger. levels set before the logger of a component is created apply to it once it is.
+func SetLogLevel(name string, level string) error {
+	lvl, err := log15.LvlFromString(level)
+	if err != nil {
+		return err
+	}
+	if name != "" && !knownComponent(name) {
+		return fmt.Errorf("unknown component %s", name)
+	}
+	muLevels.Lock()
+	defer muLevels.Unlock()
+	if name == "" {
+		defaultLevel = &lvl
+		return nil
+	}
+	levels[name] = lvl
+	return nil
+}
+
+func knownComponent(name string) bool {
+	for _, component := range LogComponents {
+		if component == name {
+			return true
+		}
+	}
+	muLogging.Lock()
+	defer muLogging.Unlock()
+	_, ok := loggers[name]
+	return ok
+}
+
+// LogLevels returns the default level and the levels of all known components.
+func LogLevels() (string, map[string]string) {
+	components := map[string]string{}
+	for _, name := range LogComponents {
+		components[name] = ""
+	}
+	muLogging.Lock()
+	for name := range loggers {
+		components[name] = ""
+	}
+	muLogging.Unlock()
+
+	muLevels.RLock()
+	defer muLevels.RUnlock()
+	for name := range levels {
+		components[name] = ""
+	}
+	for name := range components {
+		lvl, ok := levels[name]
+		if !ok {
+			lvl = defaultLogLevel()
+		}
+		components[name] = levelNames[lvl]
+	}
+	return levelNames[defaultLogLevel()], components
+}
+
+// full names of the levels as accepted by SetLogLevel
+var levelNames = map[log15.Lvl]string{
+	log15.LvlDebug: "debug",
+	log15.LvlInfo:  "info",
+	log15.LvlWarn:  "warn",
+	log15.LvlError: "error",
+	log15.LvlCrit:  "crit",
+}
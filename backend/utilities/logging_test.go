@@ -0,0 +1,58 @@
+package utilities
+
+import (
+	"testing"
+
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+func TestSetLogLevel(t *testing.T) {
+	if err := ConfigureLogging(LogConfig{StdoutOnly: true}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		muLevels.Lock()
+		levels, defaultLevel = map[string]log15.Lvl{}, nil
+		muLevels.Unlock()
+	}()
+
+	// the levels of the components can be set before their loggers exist
+	for _, name := range LogComponents {
+		if err := SetLogLevel(name, "debug"); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if _, err := GetLogger("spammer-router"); err != nil {
+		t.Fatal(err)
+	}
+	if lvl := LogLevel("spammer-router"); lvl != log15.LvlDebug {
+		t.Errorf("expected debug to apply to the logger created later, got %s", lvl)
+	}
+
+	if err := SetLogLevel("unknown-component", "debug"); err == nil {
+		t.Error("expected an unknown component to be rejected")
+	}
+	if err := SetLogLevel("slave", "verbose"); err == nil {
+		t.Error("expected an unknown level to be rejected")
+	}
+
+	if err := SetLogLevel("slave", "warn"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetLogLevel("", "error"); err != nil {
+		t.Fatal(err)
+	}
+	defaultLvl, components := LogLevels()
+	if defaultLvl != "error" {
+		t.Errorf("expected the default error, got %s", defaultLvl)
+	}
+	expected := map[string]string{"app": "debug", "slave": "warn", "spammer": "debug", "spammer-router": "debug"}
+	for name, lvl := range expected {
+		if components[name] != lvl {
+			t.Errorf("expected %s at %s, got %v", name, lvl, components)
+		}
+	}
+	if _, ok := components["unknown-component"]; ok {
+		t.Error("expected the rejected component not to be listed")
+	}
+}
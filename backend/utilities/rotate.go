@@ -0,0 +1,126 @@
+package utilities
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const rotatedLayout = "20060102T150405.000"
+
+// rotatingFile is a log file which is moved aside once it exceeds its max size or age.
+// only the newest rotated files are kept.
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	maxAge   time.Duration
+	maxFiles int
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+func openRotatingFile(path string, maxSize int64, maxAge time.Duration, maxFiles int) (*rotatingFile, error) {
+	rf := &rotatingFile{path: path, maxSize: maxSize, maxAge: maxAge, maxFiles: maxFiles}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	// an existing file is as old as its last modification for the purpose of rotation
+	rf.file, rf.size, rf.opened = file, info.Size(), info.ModTime()
+	if rf.size == 0 {
+		rf.opened = time.Now()
+	}
+	return nil
+}
+
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.size > 0 && ((rf.maxSize > 0 && rf.size+int64(len(p)) > rf.maxSize) ||
+		(rf.maxAge > 0 && time.Since(rf.opened) > rf.maxAge)) {
+		if err := rf.rotate(); err != nil {
+			// keep logging into the current file rather than losing records
+			fmt.Fprintf(os.Stderr, "unable to rotate log file %s: %v\n", rf.path, err)
+		}
+	}
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// rotate must be called with mu held
+func (rf *rotatingFile) rotate() error {
+	ext := filepath.Ext(rf.path)
+	base := strings.TrimSuffix(rf.path, ext)
+	rotated := fmt.Sprintf("%s-%s%s", base, time.Now().Format(rotatedLayout), ext)
+	if err := os.Rename(rf.path, rotated); err != nil {
+		return err
+	}
+	previous := rf.file
+	if err := rf.open(); err != nil {
+		// the renamed file is still open and written to
+		return err
+	}
+	previous.Close()
+	return rf.prune(base, ext)
+}
+
+// prune deletes the oldest rotated files beyond the max count
+func (rf *rotatingFile) prune(base string, ext string) error {
+	if rf.maxFiles <= 0 {
+		return nil
+	}
+	infos, err := ioutil.ReadDir(filepath.Dir(rf.path))
+	if err != nil {
+		return err
+	}
+	prefix := filepath.Base(base) + "-"
+	var rotated []string
+	for _, info := range infos {
+		name := info.Name()
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		// skip files of other components sharing the prefix like spammer-router for spammer
+		if _, err := time.Parse(rotatedLayout, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)); err != nil {
+			continue
+		}
+		rotated = append(rotated, name)
+	}
+	// the timestamp layout sorts chronologically
+	sort.Strings(rotated)
+	for len(rotated) > rf.maxFiles {
+		if err := os.Remove(filepath.Join(filepath.Dir(rf.path), rotated[0])); err != nil {
+			return err
+		}
+		rotated = rotated[1:]
+	}
+	return nil
+}
+
+func (rf *rotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	return rf.file.Close()
+}
@@ -11,6 +11,8 @@ import {ErrorRateChart} from "./ErrorRateChart";
 import Paper from "material-ui/Paper";
 import Divider from "material-ui/Divider";
 import {TXLog} from "./TXLog";
+import TextField from "material-ui/TextField";
+import dateformat from 'dateformat';
 
 interface Props {
     spammerStore: SpammerStore;
@@ -35,6 +37,10 @@ const styles: StyleRulesCallback = (theme: Theme) => ({
         marginTop: theme.spacing.unit * 3,
         marginBottom: theme.spacing.unit * 3,
     },
+    textField: {
+        marginRight: theme.spacing.unit * 2,
+        width: 120,
+    },
     lastMetricInfo: {
         marginTop: theme.spacing.unit * 2,
         marginBottom: theme.spacing.unit * 2,
@@ -44,6 +50,8 @@ const styles: StyleRulesCallback = (theme: Theme) => ({
 @inject("spammerStore")
 @observer
 class dashboard extends React.Component<Props & WithStyles, {}> {
+    state = {tps: '', burst: ''};
+
     componentWillMount() {
         this.props.spammerStore.connect();
     }
@@ -56,8 +64,22 @@ class dashboard extends React.Component<Props & WithStyles, {}> {
         this.props.spammerStore.stop();
     }
 
+    updateTPS = (e: any) => {
+        this.setState({tps: e.target.value});
+    }
+
+    updateBurst = (e: any) => {
+        this.setState({burst: e.target.value});
+    }
+
+    setRate = () => {
+        let tps = parseFloat(this.state.tps) || 0;
+        let burst = parseInt(this.state.burst) || 0;
+        this.props.spammerStore.setRate(tps, burst);
+    }
+
     render() {
-        let {running, connected, last_metric} = this.props.spammerStore;
+        let {running, connected, last_metric, last_latency, last_confirmation, target_tps, burst, stop_reason, events, last_error} = this.props.spammerStore;
         let classes = this.props.classes;
 
         if(!connected) {
@@ -86,10 +108,35 @@ class dashboard extends React.Component<Props & WithStyles, {}> {
                             <i className="fas fa-stop icon_margin_right"></i>
                             Stop
                         </Button>
+
+                        <TextField className={classes.textField} label="Target TPS" type="number"
+                                   value={this.state.tps} onChange={this.updateTPS}
+                                   placeholder={target_tps ? target_tps.toString() : 'unlimited'}
+                        />
+                        <TextField className={classes.textField} label="Burst" type="number"
+                                   value={this.state.burst} onChange={this.updateBurst}
+                                   placeholder={burst.toString()}
+                        />
+                        <Button className={classes.button} onClick={this.setRate} variant="raised">
+                            Set Rate
+                        </Button>
+                        {
+                            last_error &&
+                            <div className={classes.lastMetricInfo}>
+                                Error: {last_error}
+                            </div>
+                        }
+                        {
+                            !running && stop_reason &&
+                            <div className={classes.lastMetricInfo}>
+                                Stopped on its own: {stop_reason}
+                            </div>
+                        }
                         {
                             last_metric &&
                             <div className={classes.lastMetricInfo}>
-                                TPS: {Math.floor(last_metric.tps * 100) / 100},
+                                TPS: {Math.floor(last_metric.tps * 100) / 100}
+                                {' '}/ {target_tps ? target_tps : 'unlimited'},
                                 Error Rate: {Math.floor(last_metric.error_rate * 100) / 100},
                                 Bad Branch: {last_metric.bad_branch},
                                 Bad Trunk: {last_metric.bad_trunk},
@@ -100,6 +147,31 @@ class dashboard extends React.Component<Props & WithStyles, {}> {
                                 TX failed: {last_metric.txs_failed}.
                             </div>
                         }
+                        {
+                            last_confirmation &&
+                            <div className={classes.lastMetricInfo}>
+                                Confirmation Rate: {Math.floor(last_confirmation.confirmation_rate * 10000) / 100}%,
+                                Confirmed: {last_confirmation.confirmed},
+                                Pending: {last_confirmation.pending},
+                                Expired: {last_confirmation.expired},
+                                Time To Confirm (avg / p50 / p90): {Math.round(last_confirmation.confirm_time_avg)}s
+                                {' '}/ {Math.round(last_confirmation.confirm_time_p50)}s
+                                {' '}/ {Math.round(last_confirmation.confirm_time_p90)}s.
+                            </div>
+                        }
+                        {
+                            Object.keys(last_latency).length > 0 &&
+                            <div className={classes.lastMetricInfo}>
+                                Latency (p50 / p90 / p99 ms):
+                                {Object.keys(last_latency).sort().map(stage =>
+                                    <span key={stage}>
+                                        {' '}{stage}: {Math.round(last_latency[stage].p50)}
+                                        {' '}/ {Math.round(last_latency[stage].p90)}
+                                        {' '}/ {Math.round(last_latency[stage].p99)}
+                                    </span>
+                                )}
+                            </div>
+                        }
                     </Grid>
                 </Grid>
 
@@ -126,6 +198,21 @@ class dashboard extends React.Component<Props & WithStyles, {}> {
                     </Paper>
                 </Grid>
 
+                {
+                    events.length > 0 &&
+                    <Grid item xs={12} lg={12} className={classes.root}>
+                        <Paper className={classes.paper}>
+                            <h3>Events</h3>
+                            <Divider className={classes.divider}/>
+                            {events.map((event, i) =>
+                                <div key={i} className={'log_entry'}>
+                                    {dateformat(event.ts, "HH:MM:ss")} | {event.kind} | {event.msg}
+                                </div>
+                            )}
+                        </Paper>
+                    </Grid>
+                }
+
             </Grid>
         );
     }
@@ -27,6 +27,9 @@ export class TXLog extends React.Component<Props, {}> {
         return (
             <div>
                 <h3>Transactions ({txs.length})</h3>
+                <a href="/api/txs/export?format=csv">Export CSV</a>
+                {' '}
+                <a href="/api/txs/export?format=jsonl">Export JSONL</a>
                 <Divider/>
                 <br/>
                 <div className={'tx_log'}>
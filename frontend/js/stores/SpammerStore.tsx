@@ -1,16 +1,41 @@
 ///<reference path="../../../node_modules/mobx/lib/api/computed.d.ts"/>
 import {action, computed, observable, ObservableMap, runInAction} from "mobx";
 import dateformat from 'dateformat';
+import axios from 'axios';
 
 let MsgType = {
     START: 1,
     STOP: 2,
     METRIC: 3,
     STATE: 4,
+    RATE: 5,
+    EVENT: 6,
+    ERROR: 7,
+    SUBSCRIBE: 8,
+    BACKLOG_END: 9,
 };
 
+// resolution of the persisted summaries loaded into the charts
+const historyStepSeconds = 10;
+
+const maxEvents = 50;
+
 class StateMsg {
     running: boolean;
+    dry_run: boolean;
+    target_tps: number;
+    burst: number;
+    stop_reason: string;
+}
+
+class RateMsg {
+    tps: number;
+    burst: number;
+}
+
+class SubscribeMsg {
+    kinds: Array<number>;
+    sample_rate: number;
 }
 
 class WsMsg {
@@ -28,6 +53,8 @@ let MetricType = {
     INC_FAILED_TX: 5,
     INC_SUCCESSFUL_TX: 6,
     SUMMARY: 7,
+    INC_CONFIRMED_TX: 100,
+    CONFIRMATION_SUMMARY: 101,
 }
 
 export class TXData {
@@ -48,23 +75,99 @@ export class MetricSummary {
     error_rate: number;
 }
 
+class HistoryBucket {
+    ts: Date;
+    tps: number;
+    error_rate: number;
+    txs_succeeded: number;
+    txs_failed: number;
+}
+
+export class ConfirmationSummary {
+    pending: number;
+    confirmed: number;
+    expired: number;
+    confirmation_rate: number;
+    confirm_time_p50: number;
+    confirm_time_p90: number;
+    confirm_time_avg: number;
+}
+
+export class SpammerEvent {
+    kind: string;
+    msg: string;
+    data: any;
+    ts: Date;
+}
+
+export class StageLatency {
+    count: number;
+    p50: number;
+    p90: number;
+    p99: number;
+    max: number;
+}
+
 export class Metric {
     id: string;
     kind: number;
     data: any;
+    dry_run: boolean;
+    target_tps: number;
+    latency: { [stage: string]: StageLatency };
     ts: Date;
 }
 
 export class SpammerStore {
     @observable running: boolean = false;
     @observable connected: boolean = false;
+    @observable backlog_loaded: boolean = false;
+    @observable dry_run: boolean = false;
+    @observable target_tps: number = 0;
+    @observable burst: number = 0;
+    @observable stop_reason: string = '';
+    @observable events: Array<SpammerEvent> = [];
+    @observable last_error: string = '';
     @observable metrics: ObservableMap<Metric> = observable.map();
     @observable txs: ObservableMap<Metric> = observable.map();
     @observable last_metric: MetricSummary = new MetricSummary();
+    @observable last_confirmation: ConfirmationSummary = null;
+    @observable last_latency: { [stage: string]: StageLatency } = {};
     ws: WebSocket = null;
     nextMetricID: number = 0;
+    // summaries before this time are covered by the loaded history
+    historyEnd: Date = null;
+
+    // prefill the charts with the persisted summaries of the last hour up to now,
+    // replayed summaries covered by the history are skipped
+    async loadHistory() {
+        let to = Math.floor(Date.now() / 1000);
+        try {
+            let res = await axios.get(`/api/metrics?step=${historyStepSeconds}s&from=${to - 3600}&to=${to}`);
+            let buckets: Array<HistoryBucket> = res.data;
+            runInAction('load history', () => {
+                if (buckets.length > 0) {
+                    // the last bucket only covers the summaries up to the query
+                    let last = new Date(buckets[buckets.length - 1].ts).getTime() + historyStepSeconds * 1000;
+                    this.historyEnd = new Date(Math.min(last, to * 1000));
+                }
+                buckets.forEach(bucket => {
+                    this.nextMetricID++;
+                    let metric = new Metric();
+                    metric.kind = MetricType.SUMMARY;
+                    metric.ts = bucket.ts;
+                    metric.data = bucket;
+                    this.metrics.set(this.nextMetricID.toString(), metric);
+                });
+            });
+        } catch (err) {
+            console.log(err);
+        }
+    }
 
     async connect() {
+        await this.loadHistory();
+
         // pull metrics from server
         this.ws = new WebSocket(`ws://${location.host}/api/spammer`);
 
@@ -80,9 +183,19 @@ export class SpammerStore {
 
                         case MetricType.SUMMARY:
                             runInAction('add metric', () => {
+                                this.last_metric = metric.data;
+                                this.last_latency = metric.latency || {};
+                                if (this.historyEnd && new Date(metric.ts) < this.historyEnd) {
+                                    return;
+                                }
                                 this.nextMetricID++;
                                 this.metrics.set(this.nextMetricID.toString(), metric);
-                                this.last_metric = metric.data;
+                            });
+                            break;
+
+                        case MetricType.CONFIRMATION_SUMMARY:
+                            runInAction('update confirmations', () => {
+                                this.last_confirmation = metric.data;
                             });
                             break;
 
@@ -96,10 +209,37 @@ export class SpammerStore {
 
                     break;
 
+                case MsgType.BACKLOG_END:
+                    runInAction('backlog loaded', () => {
+                        this.backlog_loaded = true;
+                    });
+                    break;
+
+                case MsgType.ERROR:
+                    runInAction('set error', () => {
+                        this.last_error = obj.data.msg;
+                    });
+                    break;
+
+                case MsgType.EVENT:
+                    let event: SpammerEvent = obj.data;
+                    runInAction('add event', () => {
+                        this.events.unshift(event);
+                        if (this.events.length > maxEvents) {
+                            this.events.pop();
+                        }
+                    });
+                    break;
+
                 case MsgType.STATE:
                     let stateMsg: StateMsg = obj.data;
                     runInAction('update state', () => {
                         this.running = stateMsg.running;
+                        if (this.running) this.last_error = '';
+                        this.dry_run = stateMsg.dry_run;
+                        this.target_tps = stateMsg.target_tps;
+                        this.burst = stateMsg.burst;
+                        this.stop_reason = stateMsg.stop_reason;
                     });
                     break;
                 default:
@@ -116,6 +256,7 @@ export class SpammerStore {
         this.ws.onclose = (e) => {
             runInAction('websocket closed', () => {
                 this.connected = false;
+                this.backlog_loaded = false;
             });
         };
     }
@@ -134,6 +275,29 @@ export class SpammerStore {
         this.ws.send(JSON.stringify(msg));
     }
 
+    async setRate(tps: number, burst: number) {
+        if (!this.connected) return;
+        let rate = new RateMsg();
+        rate.tps = tps;
+        rate.burst = burst;
+        let msg = new WsMsg();
+        msg.msg_type = MsgType.RATE;
+        msg.data = rate;
+        this.ws.send(JSON.stringify(msg));
+    }
+
+    // only receive metrics of the given kinds, all kinds if empty, sampled by the given rate
+    async subscribe(kinds: Array<number>, sampleRate: number = 1) {
+        if (!this.connected) return;
+        let sub = new SubscribeMsg();
+        sub.kinds = kinds;
+        sub.sample_rate = sampleRate;
+        let msg = new WsMsg();
+        msg.msg_type = MsgType.SUBSCRIBE;
+        msg.data = sub;
+        this.ws.send(JSON.stringify(msg));
+    }
+
     @computed
     get tps(): Array<any> {
         let a = [];